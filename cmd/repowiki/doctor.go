@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+	"github.com/ikrasnodymov/repowiki/internal/hook"
+	"github.com/ikrasnodymov/repowiki/internal/lockfile"
+	"github.com/ikrasnodymov/repowiki/internal/wiki"
+)
+
+// hookLogTail is how many trailing lines of hook.log doctor scans for errors.
+const hookLogTail = 200
+
+// doctor collects check results and prints them as they come in.
+type doctor struct {
+	failed int
+}
+
+func (d *doctor) pass(name string, format string, args ...any) {
+	fmt.Printf("  [ok]   %-14s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) warn(name string, msg string, fix string) {
+	fmt.Printf("  [warn] %-14s %s\n", name, msg)
+	if fix != "" {
+		fmt.Printf("         %-14s fix: %s\n", "", fix)
+	}
+}
+
+func (d *doctor) fail(name string, msg string, fix string) {
+	d.failed++
+	fmt.Printf("  [FAIL] %-14s %s\n", name, msg)
+	if fix != "" {
+		fmt.Printf("         %-14s fix: %s\n", "", fix)
+	}
+}
+
+func handleDoctor(args []string) {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	noProbe := flags.Bool("no-probe", false, "skip the engine authentication probe (it sends a tiny prompt)")
+	flags.Parse(args)
+
+	fmt.Printf("repowiki v%s doctor\n\n", Version)
+	d := &doctor{}
+
+	gitRoot, err := git.FindRoot()
+	if err != nil {
+		d.fail("Git", "not inside a git repository", "cd into your project")
+		os.Exit(1)
+	}
+	d.pass("Git", "%s", gitRoot)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	cfg := d.checkConfig(gitRoot)
+	d.checkHook(gitRoot, cfg)
+	if cfg != nil {
+		d.checkEngines(ctx, gitRoot, cfg, !*noProbe)
+	}
+	d.checkLeftovers(gitRoot)
+	d.checkHookLog(gitRoot)
+	if cfg != nil {
+		if err := wiki.CheckBudget(gitRoot, cfg); err != nil {
+			d.warn("Budget", err.Error(), "raise the limits under \"budget\" in .repowiki/config.json")
+		}
+	}
+
+	fmt.Println()
+	if d.failed > 0 {
+		stop()
+		fmt.Printf("%d check(s) failed.\n", d.failed)
+		os.Exit(1)
+	}
+	fmt.Println("All checks passed.")
+}
+
+func (d *doctor) checkConfig(gitRoot string) *config.Config {
+	cfg, err := config.Load(gitRoot)
+	if errors.Is(err, fs.ErrNotExist) {
+		d.fail("Config", "not configured", "run 'repowiki enable'")
+		return nil
+	}
+	if err != nil {
+		d.fail("Config", err.Error(), "fix or delete .repowiki/config.json and run 'repowiki enable'")
+		return nil
+	}
+	if err := cfg.Validate(); err != nil {
+		d.fail("Config", err.Error(), "edit .repowiki/config.json")
+	} else if err := wiki.ValidateEngine(cfg); err != nil {
+		d.fail("Config", err.Error(), "edit .repowiki/config.json")
+	} else {
+		d.pass("Config", "valid (%s)", config.Path(gitRoot))
+	}
+	if modes, err := wiki.CheckPromptTemplates(gitRoot, cfg); err != nil {
+		d.fail("Prompts", err.Error(), "fix the template or run 'repowiki prompts dump --force'")
+	} else if len(modes) > 0 {
+		d.pass("Prompts", "custom templates for %s", strings.Join(modes, ", "))
+	}
+	if !cfg.Enabled {
+		d.warn("Config", "repowiki is disabled, hook runs do nothing", "run 'repowiki enable'")
+	}
+	return cfg
+}
+
+func (d *doctor) checkHook(gitRoot string, cfg *config.Config) {
+	if hooksPath := git.ConfigValue(gitRoot, "core.hooksPath"); hooksPath != "" {
+		d.fail("Hook", fmt.Sprintf("core.hooksPath is set to %s, so .git/hooks/post-commit is never run", hooksPath),
+			"call 'repowiki hooks post-commit' from your hooks manager, or unset core.hooksPath")
+	}
+	if !hook.IsInstalled(gitRoot) {
+		if cfg != nil && cfg.Enabled {
+			d.fail("Hook", "post-commit hook not installed", "run 'repowiki enable --force'")
+		}
+		return
+	}
+	if info, err := os.Stat(hook.Path(gitRoot)); err == nil && info.Mode()&0111 == 0 {
+		d.fail("Hook", "post-commit hook is not executable", "chmod +x "+hook.Path(gitRoot))
+	}
+
+	bin, ok := hook.BinaryPath(gitRoot)
+	switch {
+	case !ok:
+		d.fail("Hook", "hook block has no REPOWIKI_BIN line", "run 'repowiki enable --force'")
+	case isExecutable(bin):
+		d.pass("Hook", "installed, runs %s", bin)
+	default:
+		if path, err := exec.LookPath("repowiki"); err == nil {
+			d.warn("Hook", fmt.Sprintf("%s is missing, hook falls back to %s from $PATH", bin, path), "run 'repowiki enable --force'")
+		} else {
+			d.fail("Hook", fmt.Sprintf("%s is missing and repowiki is not on $PATH", bin), "reinstall repowiki and run 'repowiki enable --force'")
+		}
+	}
+}
+
+func (d *doctor) checkEngines(ctx context.Context, gitRoot string, cfg *config.Config, probe bool) {
+	failures := wiki.EngineFailures(gitRoot)
+	for _, name := range wiki.EngineChain(cfg) {
+		label := "Engine"
+		if name != cfg.Engine {
+			label = "Fallback"
+		}
+		e, err := wiki.LookupEngine(name)
+		if err != nil {
+			d.fail(label, err.Error(), "set \"engine\" to one of: "+strings.Join(wiki.EngineNames(), ", "))
+			continue
+		}
+		ecfg := wiki.EngineConfig(cfg, name)
+		bin, err := e.Detect(ecfg)
+		if err != nil {
+			d.fail(label, fmt.Sprintf("%s: %v", name, err), "see README, Supported Engines")
+			continue
+		}
+		version, err := e.Version(ecfg)
+		if err != nil {
+			d.fail(label, fmt.Sprintf("%s at %s does not run: %v", name, bin, err), "reinstall "+name)
+			continue
+		}
+		if version != "" {
+			bin += " (" + version + ")"
+		}
+		d.pass(label, "%s: %s", name, bin)
+
+		if !probe {
+			if f, ok := failures[name]; ok {
+				d.warn("Auth", fmt.Sprintf("%s last failed with %s at %s: %s", name, f.Kind, f.Time, f.Message), f.Hint)
+			}
+			continue
+		}
+		err = wiki.ProbeEngine(ctx, cfg, gitRoot, name)
+		if err == nil {
+			d.pass("Auth", "%s answered a test prompt", name)
+			continue
+		}
+		fix := ""
+		var ee *wiki.EngineError
+		if errors.As(err, &ee) {
+			fix = ee.Hint
+		}
+		msg, _, _ := strings.Cut(err.Error(), "\n")
+		d.fail("Auth", strings.TrimSuffix(msg, " ("+fix+")"), fix)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (d *doctor) checkLeftovers(gitRoot string) {
+	locked := lockfile.IsLocked(gitRoot)
+	switch {
+	case locked && lockfile.IsStale(gitRoot):
+		d.fail("Lock", "stale lock file left by a crashed run", "rm "+lockfile.Path(gitRoot))
+	case locked:
+		d.warn("Lock", "a repowiki run is in progress", "")
+	default:
+		d.pass("Lock", "none")
+	}
+
+	if wiki.IsSentinelPresent(gitRoot) && !locked {
+		d.fail("Sentinel", "commit sentinel left by a crashed run; every hook run is skipped",
+			"rm "+wiki.SentinelPath(gitRoot))
+	}
+}
+
+func (d *doctor) checkHookLog(gitRoot string) {
+	f, err := os.Open(filepath.Join(config.LogPath(gitRoot), "hook.log"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+		if len(lines) > hookLogTail {
+			lines = lines[1:]
+		}
+	}
+	var errs []string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "Error") {
+			errs = append(errs, l)
+		}
+	}
+	if len(errs) == 0 {
+		d.pass("Hook log", "no recent errors")
+		return
+	}
+	d.warn("Hook log", fmt.Sprintf("%d recent error(s), last: %s", len(errs), errs[len(errs)-1]), "run 'repowiki logs' for details")
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0111 != 0
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/lockfile"
+)
+
+// runAsCLIEnv makes the test binary behave as the repowiki CLI, so the git
+// hook installed by 'enable' and the update it spawns run this build.
+const runAsCLIEnv = "REPOWIKI_TEST_RUN_CLI"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runAsCLIEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// e2eRepo is a throwaway repository whose commands run this build of
+// repowiki, isolated from the user's git configuration.
+type e2eRepo struct {
+	t    *testing.T
+	root string
+	env  []string
+}
+
+func newE2ERepo(t *testing.T) *e2eRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	env := append(os.Environ(), runAsCLIEnv+"=1", "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1")
+	r := &e2eRepo{t: t, root: t.TempDir(), env: env}
+	r.git("init", "-q")
+	r.git("config", "user.email", "test@example.com")
+	r.git("config", "user.name", "test")
+	return r
+}
+
+func (r *e2eRepo) run(name string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = r.root
+	cmd.Env = r.env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("%s %s: %v\n%s", name, strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *e2eRepo) git(args ...string) string { return r.run("git", args...) }
+
+func (r *e2eRepo) repowiki(args ...string) string {
+	self, err := os.Executable()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return r.run(self, args...)
+}
+
+func (r *e2eRepo) write(path string, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit commits everything and waits for the update the hook starts in the
+// background to commit the wiki. It returns the wiki commit's subject.
+func (r *e2eRepo) commit(msg string) string {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", msg)
+	deadline := time.Now().Add(60 * time.Second)
+	for time.Now().Before(deadline) {
+		subject := r.git("log", "-1", "--format=%s")
+		if strings.HasPrefix(subject, "[repowiki]") && !lockfile.IsLocked(r.root) {
+			if parent := r.git("log", "-1", "--format=%s", "HEAD~1"); parent != msg {
+				r.t.Fatalf("wiki commit %q follows %q, want %q", subject, parent, msg)
+			}
+			return subject
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	logs, _ := os.ReadFile(filepath.Join(r.root, ".repowiki", "logs", "hook.log"))
+	r.t.Fatalf("no wiki commit after %q; HEAD is %q\nhook.log:\n%s", msg, r.git("log", "-1", "--format=%s"), logs)
+	return ""
+}
+
+func TestHookUpdatesAndCommitsWiki(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs git hooks in the background")
+	}
+	r := newE2ERepo(t)
+	r.write("main.go", "package main\n\nfunc main() {}\n")
+	r.repowiki("enable", "--engine", "fake")
+
+	// No wiki yet: the first commit generates all of it.
+	if got, want := r.commit("initial"), "[repowiki] full wiki generation"; got != want {
+		t.Errorf("first wiki commit = %q, want %q", got, want)
+	}
+	files := r.git("show", "--name-only", "--format=", "HEAD")
+	if !strings.Contains(files, ".qoder/repowiki/en/content/") {
+		t.Errorf("wiki commit has no pages:\n%s", files)
+	}
+	if strings.Contains(files, "main.go") {
+		t.Errorf("wiki commit includes source files:\n%s", files)
+	}
+	head := r.git("rev-parse", "HEAD~1")
+
+	// Then each commit updates the wiki incrementally.
+	r.write("main.go", "package main\n\nfunc main() { run() }\n\nfunc run() {}\n")
+	if got, want := r.commit("add run"), "[repowiki] update wiki for 1 changed files"; got != want {
+		t.Errorf("second wiki commit = %q, want %q", got, want)
+	}
+	if status := r.git("status", "--porcelain", "--", "main.go", ".qoder/repowiki"); status != "" {
+		t.Errorf("wiki or sources not clean after the update:\n%s", status)
+	}
+	if cfg := r.git("show", "HEAD:.repowiki/config.json"); strings.Contains(cfg, head) || !strings.Contains(cfg, r.git("rev-parse", "HEAD~1")) {
+		t.Errorf("config does not record the processed commit:\n%s", cfg)
+	}
+}
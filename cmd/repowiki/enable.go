@@ -16,9 +16,10 @@ import (
 func handleEnable(args []string) {
 	fs := flag.NewFlagSet("enable", flag.ExitOnError)
 	force := fs.Bool("force", false, "reinstall hook even if present")
-	engine := fs.String("engine", "", "AI engine: qoder, claude-code, codex")
+	engine := fs.String("engine", "", "AI engine: "+strings.Join(wiki.EngineNames(), ", "))
 	enginePath := fs.String("engine-path", "", "path to engine CLI binary")
 	model := fs.String("model", "", "model level (engine-specific)")
+	fallback := fs.String("fallback-engines", "", "comma-separated engines to try if the primary fails")
 	noAutoCommit := fs.Bool("no-auto-commit", false, "don't auto-commit wiki changes")
 	fs.Parse(args)
 
@@ -37,8 +38,8 @@ func handleEnable(args []string) {
 	// Apply flag overrides
 	engineExplicit := *engine != ""
 	if engineExplicit {
-		if !config.IsValidEngine(*engine) {
-			fmt.Fprintf(os.Stderr, "Error: unknown engine %q (valid: %s)\n", *engine, strings.Join(config.ValidEngines, ", "))
+		if !wiki.IsValidEngine(*engine) {
+			fmt.Fprintf(os.Stderr, "Error: unknown engine %q (valid: %s)\n", *engine, strings.Join(wiki.EngineNames(), ", "))
 			os.Exit(1)
 		}
 		cfg.Engine = *engine
@@ -49,11 +50,20 @@ func handleEnable(args []string) {
 	if *model != "" {
 		cfg.Model = *model
 	}
+	if *fallback != "" {
+		cfg.FallbackEngines = splitList(*fallback)
+	}
 	if *noAutoCommit {
 		cfg.AutoCommit = false
 	}
 	cfg.Enabled = true
 
+	// Validate engine-specific settings (e.g. the command template)
+	if err := wiki.ValidateEngine(cfg); err != nil && (engineExplicit || *fallback != "") {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Validate engine binary is reachable
 	binPath, findErr := wiki.FindEngineBinary(cfg)
 	if findErr != nil {
@@ -65,7 +75,7 @@ func handleEnable(args []string) {
 		}
 		// No explicit engine — auto-detect the first available one
 		detected := false
-		for _, eng := range config.EngineDetectOrder {
+		for _, eng := range wiki.DetectOrder() {
 			cfg.Engine = eng
 			cfg.EnginePath = ""
 			binPath, findErr = wiki.FindEngineBinary(cfg)
@@ -76,7 +86,7 @@ func handleEnable(args []string) {
 		}
 		if !detected {
 			fmt.Fprintf(os.Stderr, "Error: no supported AI engine found\n")
-			fmt.Fprintf(os.Stderr, "Install one of: qodercli, claude, codex\n")
+			fmt.Fprintf(os.Stderr, "Install one of: %s\n", strings.Join(wiki.DetectOrder(), ", "))
 			fmt.Fprintf(os.Stderr, "Or specify a path: repowiki enable --engine claude-code --engine-path /path/to/claude\n")
 			os.Exit(1)
 		}
@@ -103,6 +113,9 @@ func handleEnable(args []string) {
 
 	fmt.Printf("repowiki enabled in %s\n\n", gitRoot)
 	fmt.Printf("  Engine:  %s\n", cfg.Engine)
+	if len(cfg.FallbackEngines) > 0 {
+		fmt.Printf("  Fallback: %s\n", strings.Join(cfg.FallbackEngines, ", "))
+	}
 	if findErr == nil {
 		fmt.Printf("  Binary:  %s\n", binPath)
 	}
@@ -151,3 +164,15 @@ You are a technical documentation specialist. Update the repository wiki in ` +
 `
 	os.WriteFile(cmdPath, []byte(content), 0644)
 }
+
+// splitList splits a comma-separated flag value, trimming the entries and
+// dropping empty ones.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"codex", []string{"codex"}},
+		{"codex,api", []string{"codex", "api"}},
+		{" codex , api ", []string{"codex", "api"}},
+		{"codex,,api,", []string{"codex", "api"}},
+		{" , ", nil},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("splitList(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
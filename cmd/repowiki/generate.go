@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 
@@ -10,6 +12,11 @@ import (
 )
 
 func handleGenerate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	stream := fs.Bool("stream", false, "echo engine output to the terminal as it runs")
+	ignoreBudget := fs.Bool("ignore-budget", false, "run even if the daily or monthly budget is exhausted")
+	fs.Parse(args)
+
 	gitRoot, err := git.FindRoot()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: not a git repository\n")
@@ -24,10 +31,22 @@ func handleGenerate(args []string) {
 
 	head, _ := git.HeadCommit(gitRoot)
 
+	ctx, stop := signalContext()
+	defer stop()
+
 	fmt.Println("Starting full wiki generation... (this may take several minutes)")
 
-	if err := wiki.FullGenerate(gitRoot, cfg, head); err != nil {
+	opts := wiki.Options{IgnoreBudget: *ignoreBudget}
+	if *stream {
+		opts.Echo = os.Stdout
+	}
+
+	if err := wiki.FullGenerate(ctx, gitRoot, cfg, head, opts); err != nil {
+		stop()
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, wiki.ErrBudgetExhausted) {
+			fmt.Fprintf(os.Stderr, "Raise the limits in .repowiki/config.json or rerun with --ignore-budget.\n")
+		}
 		os.Exit(1)
 	}
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,10 @@ import (
 )
 
 func handleLogs(args []string) {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	run := fs.Bool("run", false, "show engine output of the latest run")
+	fs.Parse(args)
+
 	gitRoot, err := git.FindRoot()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: not a git repository\n")
@@ -18,29 +23,38 @@ func handleLogs(args []string) {
 	}
 
 	logDir := config.LogPath(gitRoot)
+	if *run {
+		logDir = config.RunLogPath(gitRoot)
+	}
 	entries, err := os.ReadDir(logDir)
 	if err != nil {
 		fmt.Println("No logs yet.")
 		return
 	}
 
+	// Only plain log files; the runs/ directory is shown with --run
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".log" {
+			names = append(names, e.Name())
+		}
+	}
+
 	// Sort by name descending (newest first)
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Name() > entries[j].Name()
-	})
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
 
 	// Show latest log
-	if len(entries) == 0 {
+	if len(names) == 0 {
 		fmt.Println("No logs yet.")
 		return
 	}
 
-	latest := entries[0]
-	data, err := os.ReadFile(filepath.Join(logDir, latest.Name()))
+	latest := names[0]
+	data, err := os.ReadFile(filepath.Join(logDir, latest))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading log: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("=== %s ===\n%s", latest.Name(), string(data))
+	fmt.Printf("=== %s ===\n%s", latest, string(data))
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 const Version = "0.1.0"
@@ -28,6 +31,14 @@ func main() {
 		handleHooks(os.Args[2:])
 	case "logs":
 		handleLogs(os.Args[2:])
+	case "usage":
+		handleUsage(os.Args[2:])
+	case "doctor":
+		handleDoctor(os.Args[2:])
+	case "outline":
+		handleOutline(os.Args[2:])
+	case "prompts":
+		handlePrompts(os.Args[2:])
 	case "version", "--version", "-v":
 		fmt.Printf("repowiki v%s\n", Version)
 	case "help", "--help", "-h":
@@ -51,17 +62,43 @@ Commands:
   disable     Disable repowiki (remove git hook)
   status      Show current status and configuration
   generate    Run full wiki generation
+  outline     Show or create the page outline for two-phase generation
   update      Run incremental wiki update for recent changes
   logs        Show latest generation log
+  doctor      Diagnose hook, engine, auth and leftover lock problems
+  usage       Show tokens, turns and cost of engine runs per month
+  prompts     List prompt templates; 'prompts dump' writes the defaults for editing
   version     Show version
 
 Flags for 'enable':
-  --engine            AI engine: qoder, claude-code, codex (default: qoder)
+  --engine            AI engine: qoder, claude-code, codex, command, api, local, fake (default: qoder)
   --engine-path       Path to engine CLI binary
   --model             Model level (engine-specific)
+  --fallback-engines  Comma-separated engines to try if the primary fails
   --force             Reinstall hook even if already present
   --no-auto-commit    Don't auto-commit wiki changes
 
+Flags for 'generate':
+  --stream            Echo engine output to the terminal as it runs
+  --ignore-budget     Run even if the daily or monthly budget is exhausted
+
+Flags for 'outline':
+  --force             Replace the existing outline with a new one
+  --stream            Echo engine output to the terminal as it runs
+  --ignore-budget     Run even if the daily or monthly budget is exhausted
+
+Flags for 'logs':
+  --run               Show engine output of the latest run
+
+Flags for 'doctor':
+  --no-probe          Skip the engine auth probe (it sends a tiny prompt)
+
+Flags for 'prompts dump':
+  --force             Overwrite templates that already exist
+
+Flags for 'usage':
+  --month             Only show one month (YYYY-MM)
+
 Flags for 'update':
   --commit            Specific commit hash to process
   --from-hook         Internal: indicates hook-triggered run
@@ -76,3 +113,9 @@ Examples:
   repowiki disable                               # Remove hook
 `, Version)
 }
+
+// signalContext returns a context cancelled on SIGINT/SIGTERM, so a running
+// engine is stopped and deferred cleanup (lock, sentinel) still runs.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+	"github.com/ikrasnodymov/repowiki/internal/wiki"
+)
+
+// handleOutline shows the page outline of two-phase generation, asking the
+// engine for one if there is none yet.
+func handleOutline(args []string) {
+	flags := flag.NewFlagSet("outline", flag.ExitOnError)
+	force := flags.Bool("force", false, "replace the existing outline with a new one")
+	stream := flags.Bool("stream", false, "echo engine output to the terminal as it runs")
+	ignoreBudget := flags.Bool("ignore-budget", false, "run even if the daily or monthly budget is exhausted")
+	flags.Parse(args)
+
+	gitRoot, err := git.FindRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: not a git repository\n")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(gitRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: repowiki not configured. Run 'repowiki enable' first.\n")
+		os.Exit(1)
+	}
+
+	outline, err := wiki.LoadOutline(gitRoot)
+	if err == nil && !*force {
+		printOutline(outline)
+		return
+	}
+	if err != nil && !isNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	fmt.Println("Generating wiki outline... (this may take a few minutes)")
+	opts := wiki.Options{IgnoreBudget: *ignoreBudget}
+	if *stream {
+		opts.Echo = os.Stdout
+	}
+	outline, err = wiki.GenerateOutline(ctx, gitRoot, cfg, opts)
+	if err != nil {
+		stop()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	printOutline(outline)
+	fmt.Printf("\nReview and edit %s/%s, then run 'repowiki generate'.\n", config.ConfigDir, config.OutlineFile)
+	if cfg.Generation != config.GenerationOutline {
+		fmt.Printf("Set \"generation\": %q in %s/%s to generate from the outline.\n", config.GenerationOutline, config.ConfigDir, config.ConfigFile)
+	}
+}
+
+func printOutline(o *wiki.Outline) {
+	for _, p := range o.Pages {
+		fmt.Printf("  %s\n", p.Path)
+		if p.Description != "" {
+			fmt.Printf("      %s\n", p.Description)
+		}
+	}
+}
+
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
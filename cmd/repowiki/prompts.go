@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+	"github.com/ikrasnodymov/repowiki/internal/wiki"
+)
+
+// handlePrompts lists the prompt templates in use or, with "dump", writes
+// the built-in ones to .repowiki/prompts/ for editing.
+func handlePrompts(args []string) {
+	gitRoot, err := git.FindRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: not a git repository\n")
+		os.Exit(1)
+	}
+
+	if len(args) > 0 && args[0] == "dump" {
+		dumpPrompts(gitRoot, args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] != "list" {
+		fmt.Fprintf(os.Stderr, "Unknown prompts command: %s (valid: list, dump)\n", args[0])
+		os.Exit(1)
+	}
+
+	for _, mode := range wiki.PromptModes() {
+		path := wiki.PromptTemplatePath(gitRoot, mode)
+		source := "built-in"
+		if _, err := os.Stat(path); err == nil {
+			source, _ = filepath.Rel(gitRoot, path)
+		}
+		fmt.Printf("  %-12s %s\n", mode, source)
+	}
+	if cfg, err := config.Load(gitRoot); err == nil {
+		if _, err := wiki.CheckPromptTemplates(gitRoot, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func dumpPrompts(gitRoot string, args []string) {
+	fs := flag.NewFlagSet("prompts dump", flag.ExitOnError)
+	force := fs.Bool("force", false, "overwrite existing templates")
+	fs.Parse(args)
+
+	modes := fs.Args()
+	if len(modes) == 0 {
+		modes = wiki.PromptModes()
+	}
+	if err := os.MkdirAll(config.PromptsPath(gitRoot), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, mode := range modes {
+		text, err := wiki.DefaultPromptTemplate(mode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		path := wiki.PromptTemplatePath(gitRoot, mode)
+		rel, _ := filepath.Rel(gitRoot, path)
+		if _, err := os.Stat(path); err == nil && !*force {
+			fmt.Printf("  skipped %s (exists, use --force to overwrite)\n", rel)
+			continue
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  wrote %s\n", rel)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/ikrasnodymov/repowiki/internal/config"
 	"github.com/ikrasnodymov/repowiki/internal/git"
@@ -36,6 +38,20 @@ func handleStatus(args []string) {
 
 	// Engine
 	fmt.Printf("  Engine:       %s\n", cfg.Engine)
+	if len(cfg.FallbackEngines) > 0 {
+		fmt.Printf("  Fallbacks:    %s\n", strings.Join(cfg.FallbackEngines, ", "))
+	}
+
+	switch cfg.Generation {
+	case config.GenerationSections:
+		fmt.Printf("  Generation:   per section, %d sections, %d at a time\n", len(cfg.WikiSections()), cfg.Workers())
+	case config.GenerationOutline:
+		if o, err := wiki.LoadOutline(gitRoot); err == nil {
+			fmt.Printf("  Generation:   two-phase, %d pages in %s, %d at a time\n", len(o.Pages), config.OutlineFile, cfg.Workers())
+		} else {
+			fmt.Printf("  Generation:   two-phase, no usable outline yet (%v)\n", err)
+		}
+	}
 
 	// Hook
 	if hook.IsInstalled(gitRoot) {
@@ -45,11 +61,52 @@ func handleStatus(args []string) {
 	}
 
 	// Engine binary
-	binPath, engineErr := wiki.FindEngineBinary(cfg)
-	if engineErr == nil {
-		fmt.Printf("  Binary:       %s\n", binPath)
-	} else {
+	if eng, err := wiki.LookupEngine(cfg.Engine); err != nil {
+		fmt.Printf("  Binary:       unknown engine (%s)\n", cfg.Engine)
+	} else if binPath, err := eng.Detect(cfg); err != nil {
 		fmt.Printf("  Binary:       not found (%s)\n", cfg.Engine)
+	} else {
+		fmt.Printf("  Binary:       %s\n", binPath)
+		if version, err := eng.Version(cfg); err == nil && version != "" {
+			fmt.Printf("  Version:      %s\n", version)
+		}
+	}
+	if cmdLine, err := wiki.CommandLine(cfg, gitRoot); err == nil && cmdLine != "" {
+		fmt.Printf("  Command:      %s\n", cmdLine)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("  Config error: %v\n", err)
+	}
+	if err := wiki.ValidateEngine(cfg); err != nil {
+		fmt.Printf("  Config error: %v\n", err)
+	}
+
+	// Failures recorded by background runs
+	failures := wiki.EngineFailures(gitRoot)
+	for _, name := range wiki.EngineChain(cfg) {
+		f, ok := failures[name]
+		if !ok {
+			continue
+		}
+		if f.Kind == wiki.ErrAuth {
+			fmt.Printf("  Auth:         FAILED for %s at %s\n", name, f.Time)
+			if f.Hint != "" {
+				fmt.Printf("                fix: %s\n", f.Hint)
+			}
+			continue
+		}
+		fmt.Printf("  Last error:   %s %s at %s: %s\n", name, f.Kind, f.Time, f.Message)
+	}
+
+	// Budget
+	if b := cfg.Budget; b != nil {
+		if day, month, err := wiki.Spending(gitRoot, time.Now()); err == nil {
+			fmt.Printf("  Budget:       today %s, this month %s\n",
+				formatSpend(day, b.DailyTokens, b.DailyCostUSD), formatSpend(month, b.MonthlyTokens, b.MonthlyCostUSD))
+		}
+		if err := wiki.CheckBudget(gitRoot, cfg); err != nil {
+			fmt.Printf("  WARNING:      %v; hook runs are skipped\n", err)
+		}
 	}
 
 	// Wiki
@@ -77,6 +134,19 @@ func handleStatus(args []string) {
 	}
 }
 
+// formatSpend renders spend against its limits, e.g. "12000/50000 tokens, $1.20/$5.00".
+func formatSpend(s wiki.Spend, tokenLimit int, costLimit float64) string {
+	tokens := fmt.Sprintf("%d tokens", s.Tokens)
+	if tokenLimit > 0 {
+		tokens = fmt.Sprintf("%d/%d tokens", s.Tokens, tokenLimit)
+	}
+	cost := fmt.Sprintf("$%.2f", s.CostUSD)
+	if costLimit > 0 {
+		cost = fmt.Sprintf("$%.2f/$%.2f", s.CostUSD, costLimit)
+	}
+	return tokens + ", " + cost
+}
+
 func countMdFiles(dir string) int {
 	count := 0
 	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -37,7 +38,11 @@ func handleUpdate(args []string) {
 		}
 	}
 
-	if err := runUpdateCycle(gitRoot, cfg, hash, *fromHook); err != nil {
+	ctx, stop := signalContext()
+	defer stop()
+
+	if err := runUpdateCycle(ctx, gitRoot, cfg, hash, *fromHook); err != nil {
+		stop()
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -46,7 +51,7 @@ func handleUpdate(args []string) {
 	// If a commit happened while we held the lock, its hook exited silently.
 	// Re-run to pick up those missed changes.
 	if *fromHook {
-		for i := 0; i < 5; i++ { // cap retries to avoid runaway loops
+		for i := 0; i < 5 && ctx.Err() == nil; i++ { // cap retries to avoid runaway loops
 			cfg, err = config.Load(gitRoot)
 			if err != nil {
 				break
@@ -58,7 +63,7 @@ func handleUpdate(args []string) {
 			if !hasUnprocessedCommits(gitRoot, cfg, head) {
 				break
 			}
-			if err := runUpdateCycle(gitRoot, cfg, head, true); err != nil {
+			if err := runUpdateCycle(ctx, gitRoot, cfg, head, true); err != nil {
 				break
 			}
 		}
@@ -76,47 +81,69 @@ func hasUnprocessedCommits(gitRoot string, cfg *config.Config, head string) bool
 		return false
 	}
 	// Check that the gap contains actual code changes, not just repowiki commits
-	files, err := git.ChangedFilesSince(gitRoot, cfg.LastCommitHash)
+	changes, err := git.ChangesSince(gitRoot, cfg.LastCommitHash)
 	if err != nil {
 		return false
 	}
-	files = filterExcluded(files, cfg.ExcludedPaths)
-	return len(files) > 0
+	return len(filterExcludedChanges(changes, cfg.ExcludedPaths)) > 0
 }
 
 // runUpdateCycle performs a single update cycle: detect changes, run generation.
-func runUpdateCycle(gitRoot string, cfg *config.Config, hash string, fromHook bool) error {
-	var changedFiles []string
+func runUpdateCycle(ctx context.Context, gitRoot string, cfg *config.Config, hash string, fromHook bool) error {
+	var changes []git.FileChange
 	var err error
 	if cfg.LastCommitHash != "" && cfg.LastCommitHash != hash {
-		changedFiles, err = git.ChangedFilesSince(gitRoot, cfg.LastCommitHash)
+		changes, err = git.ChangesSince(gitRoot, cfg.LastCommitHash)
 	} else {
-		changedFiles, err = git.ChangedFilesInCommit(gitRoot, hash)
+		changes, err = git.ChangesInCommit(gitRoot, hash)
 	}
 	if err != nil {
 		return fmt.Errorf("detecting changes: %w", err)
 	}
 
-	changedFiles = filterExcluded(changedFiles, cfg.ExcludedPaths)
+	changes = filterExcludedChanges(changes, cfg.ExcludedPaths)
 
-	if len(changedFiles) == 0 {
+	if len(changes) == 0 {
 		if !fromHook {
 			fmt.Println("No relevant file changes detected.")
 		}
 		return nil
 	}
 
-	if !wiki.Exists(gitRoot, cfg) || len(changedFiles) > cfg.FullGenerateThreshold {
+	if !wiki.Exists(gitRoot, cfg) || len(changes) > cfg.FullGenerateThreshold {
 		if !fromHook {
-			fmt.Printf("Running full wiki generation (%d files changed)...\n", len(changedFiles))
+			fmt.Printf("Running full wiki generation (%d files changed)...\n", len(changes))
 		}
-		return wiki.FullGenerate(gitRoot, cfg, hash)
+		return wiki.FullGenerate(ctx, gitRoot, cfg, hash, wiki.Options{})
 	}
 
 	if !fromHook {
-		fmt.Printf("Updating wiki for %d changed files...\n", len(changedFiles))
+		fmt.Printf("Updating wiki for %d changed files...\n", len(changes))
+	}
+	return wiki.IncrementalUpdate(ctx, gitRoot, cfg, changes, hash, wiki.Options{})
+}
+
+// filterExcludedChanges drops changes to excluded paths. A file renamed
+// into an excluded path counts as deleted, one renamed out of it as added.
+func filterExcludedChanges(changes []git.FileChange, excluded []string) []git.FileChange {
+	var result []git.FileChange
+	for _, c := range changes {
+		newOK := len(filterExcluded([]string{c.Path}, excluded)) > 0
+		if c.Status == git.StatusRenamed {
+			oldOK := len(filterExcluded([]string{c.OldPath}, excluded)) > 0
+			switch {
+			case oldOK && !newOK:
+				c = git.FileChange{Status: git.StatusDeleted, Path: c.OldPath}
+				newOK = true
+			case !oldOK && newOK:
+				c = git.FileChange{Status: git.StatusAdded, Path: c.Path}
+			}
+		}
+		if newOK {
+			result = append(result, c)
+		}
 	}
-	return wiki.IncrementalUpdate(gitRoot, cfg, changedFiles, hash)
+	return result
 }
 
 func filterExcluded(files []string, excluded []string) []string {
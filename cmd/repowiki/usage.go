@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ikrasnodymov/repowiki/internal/git"
+	"github.com/ikrasnodymov/repowiki/internal/wiki"
+)
+
+// usageRow aggregates the runs of one engine/model within a month.
+type usageRow struct {
+	month, engine, model string
+	runs, failed         int
+	input, output        int
+	turns                int
+	cost                 float64
+}
+
+func handleUsage(args []string) {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	month := fs.String("month", "", "only show this month (YYYY-MM)")
+	fs.Parse(args)
+
+	gitRoot, err := git.FindRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: not a git repository\n")
+		os.Exit(1)
+	}
+
+	records, err := wiki.LoadRunRecords(gitRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading run records: %v\n", err)
+		os.Exit(1)
+	}
+
+	rows := map[string]*usageRow{}
+	for _, r := range records {
+		m := r.Time
+		if len(m) >= 7 {
+			m = m[:7]
+		}
+		if *month != "" && m != *month {
+			continue
+		}
+		key := m + "\x00" + r.Engine + "\x00" + r.Model
+		row, ok := rows[key]
+		if !ok {
+			row = &usageRow{month: m, engine: r.Engine, model: r.Model}
+			rows[key] = row
+		}
+		row.runs++
+		if r.Status != wiki.RunStatusOK {
+			row.failed++
+		}
+		row.input += r.InputTokens + r.CacheReadTokens
+		row.output += r.OutputTokens
+		row.turns += r.Turns
+		row.cost += r.CostUSD
+	}
+	if len(rows) == 0 {
+		fmt.Println("No engine runs recorded yet.")
+		return
+	}
+
+	sorted := make([]*usageRow, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, row)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.month != b.month {
+			return a.month > b.month
+		}
+		if a.engine != b.engine {
+			return a.engine < b.engine
+		}
+		return a.model < b.model
+	})
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MONTH\tENGINE\tMODEL\tRUNS\tFAILED\tINPUT\tOUTPUT\tTURNS\tCOST")
+	totals := map[string]float64{}
+	for _, row := range sorted {
+		model := row.model
+		if model == "" {
+			model = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t$%.2f\n",
+			row.month, row.engine, model, row.runs, row.failed, row.input, row.output, row.turns, row.cost)
+		totals[row.month] += row.cost
+	}
+	tw.Flush()
+
+	months := make([]string, 0, len(totals))
+	for m := range totals {
+		months = append(months, m)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(months)))
+	var parts []string
+	for _, m := range months {
+		parts = append(parts, fmt.Sprintf("%s $%.2f", m, totals[m]))
+	}
+	fmt.Printf("\nTotal cost per month: %s\n", strings.Join(parts, ", "))
+	fmt.Println("Cost is only reported by claude-code; other engines show tokens only.")
+}
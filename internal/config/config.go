@@ -5,45 +5,251 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/pathmatch"
 )
 
 const (
-	ConfigDir  = ".repowiki"
-	ConfigFile = "config.json"
-	LogDir     = "logs"
+	ConfigDir   = ".repowiki"
+	ConfigFile  = "config.json"
+	LogDir      = "logs"
+	RunLogDir   = "runs"
+	RunsFile    = "runs.jsonl"
+	PromptsDir  = "prompts"
+	OutlineFile = "outline.json"
 
-	EngineQoder     = "qoder"
+	EngineQoder      = "qoder"
 	EngineClaudeCode = "claude-code"
-	EngineCodex     = "codex"
+	EngineCodex      = "codex"
+	EngineCommand    = "command"
+	EngineAPI        = "api"
+	EngineLocal      = "local"
+	EngineFake       = "fake"
+
+	WriteGuardRevert = "revert"
+	WriteGuardAbort  = "abort"
+	WriteGuardWarn   = "warn"
+	WriteGuardOff    = "off"
+
+	GenerationSingle   = "single"
+	GenerationSections = "sections"
+	GenerationOutline  = "outline"
+
+	DefaultTimeoutMinutes      = 20
+	DefaultMaxRetries          = 2
+	DefaultRetryBackoffSeconds = 30
+	DefaultDiffMaxBytes        = 8192
 )
 
 type Config struct {
-	Enabled               bool     `json:"enabled"`
-	Engine                string   `json:"engine"`
-	EnginePath            string   `json:"engine_path,omitempty"`
-	Model                 string   `json:"model"`
-	MaxTurns              int      `json:"max_turns"`
-	Language              string   `json:"language"`
-	AutoCommit            bool     `json:"auto_commit"`
-	CommitPrefix          string   `json:"commit_prefix"`
-	ExcludedPaths         []string `json:"excluded_paths"`
-	WikiPath              string   `json:"wiki_path"`
-	FullGenerateThreshold int      `json:"full_generate_threshold"`
-	LastRun               string   `json:"last_run,omitempty"`
-	LastCommitHash        string   `json:"last_commit_hash,omitempty"`
+	Enabled               bool                      `json:"enabled"`
+	Engine                string                    `json:"engine"`
+	EnginePath            string                    `json:"engine_path,omitempty"`
+	Command               []string                  `json:"command,omitempty"`
+	FallbackEngines       []string                  `json:"fallback_engines,omitempty"`
+	API                   *APIConfig                `json:"api,omitempty"`
+	Local                 *LocalConfig              `json:"local,omitempty"`
+	Budget                *BudgetConfig             `json:"budget,omitempty"`
+	Engines               map[string]*EngineOptions `json:"engines,omitempty"`
+	Model                 string                    `json:"model"`
+	MaxTurns              int                       `json:"max_turns"`
+	TimeoutMinutes        int                       `json:"timeout_minutes"`
+	MaxRetries            int                       `json:"max_retries"`
+	RetryBackoffSeconds   int                       `json:"retry_backoff_seconds"`
+	Language              string                    `json:"language"`
+	AutoCommit            bool                      `json:"auto_commit"`
+	CommitPrefix          string                    `json:"commit_prefix"`
+	ExcludedPaths         []string                  `json:"excluded_paths"`
+	WikiPath              string                    `json:"wiki_path"`
+	FullGenerateThreshold int                       `json:"full_generate_threshold"`
+	DiffMaxBytes          int                       `json:"diff_max_bytes"`
+	WriteGuard            string                    `json:"write_guard"`
+	Sections              []Section                 `json:"sections,omitempty"`
+	SectionRules          []SectionRule             `json:"section_rules,omitempty"`
+	Generation            string                    `json:"generation,omitempty"`
+	Concurrency           int                       `json:"concurrency,omitempty"`
+	LastRun               string                    `json:"last_run,omitempty"`
+	LastCommitHash        string                    `json:"last_commit_hash,omitempty"`
+}
+
+// APIConfig configures the "api" engine, which calls an OpenAI-compatible or
+// Anthropic Messages HTTP endpoint directly instead of an agent CLI.
+type APIConfig struct {
+	Provider  string `json:"provider"`
+	BaseURL   string `json:"base_url,omitempty"`
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+}
+
+// LocalConfig configures the "local" engine, which targets an on-prem Ollama
+// or llama.cpp-compatible server.
+type LocalConfig struct {
+	URL string `json:"url,omitempty"`
+	// API is "ollama" (native /api/chat) or "openai" (/v1/chat/completions,
+	// as served by llama.cpp).
+	API         string `json:"api,omitempty"`
+	ContextSize int    `json:"context_size,omitempty"`
+}
+
+// EngineOptions are settings for one engine, keyed by engine name under
+// "engines". Model and MaxTurns override the top-level values for that
+// engine; each engine maps the options to its own flags and rejects those it
+// can't honour.
+type EngineOptions struct {
+	Model        string            `json:"model,omitempty"`
+	MaxTurns     int               `json:"max_turns,omitempty"`
+	AllowedTools []string          `json:"allowed_tools,omitempty"`
+	ExtraArgs    []string          `json:"extra_args,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	// PromptDelivery is how the prompt reaches the engine process; see
+	// PromptDeliveryAuto and friends.
+	PromptDelivery string `json:"prompt_delivery,omitempty"`
+}
+
+// Section is one entry of the wiki outline: a top-level page, or a directory
+// of pages when Directory is set.
+type Section struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Directory   bool   `json:"directory,omitempty"`
+	// Sources are gitignore-style globs of the files the section documents;
+	// a change to a matching file marks the section as affected.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// DefaultSections is the outline used when config.json has no "sections".
+func DefaultSections() []Section {
+	return []Section{
+		{Title: "System Overview", Description: "project purpose, high-level architecture"},
+		{Title: "Technology Stack", Description: "languages, frameworks, key dependencies"},
+		{Title: "Getting Started", Description: "setup, installation, running"},
+		{Title: "Backend Architecture", Description: "server structure, API design, database, etc.", Directory: true},
+		{Title: "Frontend Architecture", Description: "UI components, state management, etc.", Directory: true},
+		{Title: "Core Features", Description: "each major feature documented individually", Directory: true},
+		{Title: "API Reference", Description: "endpoints, request/response formats", Directory: true},
+		{Title: "Configuration Management", Description: "environment variables, config files"},
+	}
+}
+
+// WikiSections returns the configured outline, or the default one.
+func (c *Config) WikiSections() []Section {
+	if len(c.Sections) > 0 {
+		return c.Sections
+	}
+	return DefaultSections()
+}
+
+// SectionRule maps changed files to a wiki section or page during change
+// detection. Exactly one of Glob (gitignore-style) and Regex (RE2, matched
+// against the slash-separated repository path) is set.
+type SectionRule struct {
+	Glob    string `json:"glob,omitempty"`
+	Regex   string `json:"regex,omitempty"`
+	Section string `json:"section"`
+	// Weight is added to the section's score for each matching file; 0
+	// means 1. Negative weights count against a section.
+	Weight int `json:"weight,omitempty"`
+}
+
+// DefaultSectionRules guess the sections of the default outline from
+// common web project layouts.
+func DefaultSectionRules() []SectionRule {
+	return []SectionRule{
+		{Regex: `(?i)(backend|server|src/api)/`, Section: "Backend Architecture"},
+		{Regex: `(?i)(frontend|src/components|src/app)/`, Section: "Frontend Architecture"},
+		{Regex: `(?i)(api|routes|endpoints)/`, Section: "API Reference"},
+		{Regex: `(?i)config|\.env|settings`, Section: "Configuration Management"},
+		{Regex: `(?i)(readme\.md|package\.json|pyproject\.toml)$`, Section: "System Overview"},
+		{Regex: `(?i)(database|models|migrations)/`, Section: "Backend Architecture"},
+	}
+}
+
+// DetectionRules returns the configured section rules or, with neither
+// rules nor a custom outline configured, the default ones. The default
+// rules name the default sections, so they don't apply to a custom
+// outline.
+func (c *Config) DetectionRules() []SectionRule {
+	if len(c.SectionRules) > 0 {
+		return c.SectionRules
+	}
+	if len(c.Sections) == 0 {
+		return DefaultSectionRules()
+	}
+	return nil
+}
+
+// Names of EngineOptions fields as they appear in config.json.
+const (
+	OptionModel          = "model"
+	OptionMaxTurns       = "max_turns"
+	OptionAllowedTools   = "allowed_tools"
+	OptionExtraArgs      = "extra_args"
+	OptionEnv            = "env"
+	OptionPromptDelivery = "prompt_delivery"
+)
+
+// Values of EngineOptions.PromptDelivery. Auto passes short prompts as an
+// argument and switches to stdin or a temp file, whichever the engine
+// supports, once a prompt is too long for the command line.
+const (
+	PromptDeliveryAuto  = "auto"
+	PromptDeliveryArgv  = "argv"
+	PromptDeliveryStdin = "stdin"
+	PromptDeliveryFile  = "file"
+)
+
+// Set lists the options that are set, by config name.
+func (o *EngineOptions) Set() []string {
+	var set []string
+	if o.Model != "" {
+		set = append(set, OptionModel)
+	}
+	if o.MaxTurns != 0 {
+		set = append(set, OptionMaxTurns)
+	}
+	if len(o.AllowedTools) > 0 {
+		set = append(set, OptionAllowedTools)
+	}
+	if len(o.ExtraArgs) > 0 {
+		set = append(set, OptionExtraArgs)
+	}
+	if len(o.Env) > 0 {
+		set = append(set, OptionEnv)
+	}
+	if o.PromptDelivery != "" && o.PromptDelivery != PromptDeliveryAuto {
+		set = append(set, OptionPromptDelivery)
+	}
+	return set
+}
+
+// BudgetConfig caps engine spend. Zero fields are unlimited. Tokens count uncached
+// input plus output; cache reads are billed at a fraction and not counted.
+// Days and months are UTC.
+type BudgetConfig struct {
+	RunTokens      int     `json:"run_tokens,omitempty"`
+	RunCostUSD     float64 `json:"run_cost_usd,omitempty"`
+	DailyTokens    int     `json:"daily_tokens,omitempty"`
+	DailyCostUSD   float64 `json:"daily_cost_usd,omitempty"`
+	MonthlyTokens  int     `json:"monthly_tokens,omitempty"`
+	MonthlyCostUSD float64 `json:"monthly_cost_usd,omitempty"`
 }
 
 func Default() *Config {
 	return &Config{
-		Enabled:      true,
-		Engine:       EngineQoder,
-		EnginePath:   "",
-		Model:        "",
-		MaxTurns:     50,
-		Language:     "en",
-		AutoCommit:   true,
-		CommitPrefix: "[repowiki]",
+		Enabled:             true,
+		Engine:              EngineQoder,
+		EnginePath:          "",
+		Model:               "",
+		MaxTurns:            50,
+		TimeoutMinutes:      DefaultTimeoutMinutes,
+		MaxRetries:          DefaultMaxRetries,
+		RetryBackoffSeconds: DefaultRetryBackoffSeconds,
+		Language:            "en",
+		AutoCommit:          true,
+		CommitPrefix:        "[repowiki]",
 		ExcludedPaths: []string{
 			".qoder/repowiki/",
 			".repowiki/",
@@ -53,24 +259,11 @@ func Default() *Config {
 		},
 		WikiPath:              ".qoder/repowiki",
 		FullGenerateThreshold: 20,
+		DiffMaxBytes:          DefaultDiffMaxBytes,
+		WriteGuard:            WriteGuardRevert,
 	}
 }
 
-var ValidEngines = []string{EngineQoder, EngineClaudeCode, EngineCodex}
-
-// EngineDetectOrder is the order in which engines are tried during auto-detection.
-// claude-code first because it's the most commonly available.
-var EngineDetectOrder = []string{EngineClaudeCode, EngineQoder, EngineCodex}
-
-func IsValidEngine(engine string) bool {
-	for _, e := range ValidEngines {
-		if e == engine {
-			return true
-		}
-	}
-	return false
-}
-
 func Dir(gitRoot string) string {
 	return filepath.Join(gitRoot, ConfigDir)
 }
@@ -83,6 +276,26 @@ func LogPath(gitRoot string) string {
 	return filepath.Join(Dir(gitRoot), LogDir)
 }
 
+// RunLogPath is the directory holding per-run engine output logs.
+func RunLogPath(gitRoot string) string {
+	return filepath.Join(LogPath(gitRoot), RunLogDir)
+}
+
+// RunsPath is the file holding one usage record per engine run.
+func RunsPath(gitRoot string) string {
+	return filepath.Join(Dir(gitRoot), RunsFile)
+}
+
+// OutlinePath is the page outline used by two-phase generation.
+func OutlinePath(gitRoot string) string {
+	return filepath.Join(Dir(gitRoot), OutlineFile)
+}
+
+// PromptsPath is the directory holding user overrides of the prompt templates.
+func PromptsPath(gitRoot string) string {
+	return filepath.Join(Dir(gitRoot), PromptsDir)
+}
+
 func Load(gitRoot string) (*Config, error) {
 	data, err := os.ReadFile(Path(gitRoot))
 	if err != nil {
@@ -96,9 +309,147 @@ func Load(gitRoot string) (*Config, error) {
 	if cfg.Engine == "" {
 		cfg.Engine = EngineQoder
 	}
+	// Migration: old configs without write guard get the default
+	if cfg.WriteGuard == "" {
+		cfg.WriteGuard = WriteGuardRevert
+	}
+	// Migration: old configs without timeout get the default
+	if cfg.TimeoutMinutes == 0 {
+		cfg.TimeoutMinutes = DefaultTimeoutMinutes
+	}
+	// Migration: old configs without max_retries get the default; an
+	// explicit 0 still disables retries.
+	var set struct {
+		MaxRetries *int `json:"max_retries"`
+	}
+	if json.Unmarshal(data, &set) == nil && set.MaxRetries == nil {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
 	return &cfg, nil
 }
 
+// Validate checks settings that don't depend on the chosen engine.
+func (c *Config) Validate() error {
+	switch c.WriteGuard {
+	case WriteGuardRevert, WriteGuardAbort, WriteGuardWarn, WriteGuardOff:
+	default:
+		return fmt.Errorf("write_guard must be %q, %q, %q or %q", WriteGuardRevert, WriteGuardAbort, WriteGuardWarn, WriteGuardOff)
+	}
+	if b := c.Budget; b != nil {
+		if b.RunTokens < 0 || b.DailyTokens < 0 || b.MonthlyTokens < 0 ||
+			b.RunCostUSD < 0 || b.DailyCostUSD < 0 || b.MonthlyCostUSD < 0 {
+			return fmt.Errorf("budget limits must not be negative")
+		}
+	}
+	switch c.Generation {
+	case "", GenerationSingle, GenerationSections, GenerationOutline:
+	default:
+		return fmt.Errorf("generation must be %q, %q or %q", GenerationSingle, GenerationSections, GenerationOutline)
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative")
+	}
+	titles := map[string]bool{}
+	for i, sec := range c.Sections {
+		title := strings.TrimSpace(sec.Title)
+		if title == "" {
+			return fmt.Errorf("sections[%d] has no title", i)
+		}
+		if strings.ContainsAny(title, "/\\") {
+			return fmt.Errorf("section %q: title must not contain path separators", title)
+		}
+		if titles[title] {
+			return fmt.Errorf("section %q is defined twice", title)
+		}
+		titles[title] = true
+		for _, g := range sec.Sources {
+			if !pathmatch.Valid(g) {
+				return fmt.Errorf("section %q: invalid source glob %q", title, g)
+			}
+		}
+	}
+	for i, r := range c.SectionRules {
+		if strings.TrimSpace(r.Section) == "" {
+			return fmt.Errorf("section_rules[%d] has no section", i)
+		}
+		switch {
+		case (r.Glob == "") == (r.Regex == ""):
+			return fmt.Errorf("section_rules[%d]: set exactly one of glob and regex", i)
+		case r.Glob != "" && !pathmatch.Valid(r.Glob):
+			return fmt.Errorf("section_rules[%d]: invalid glob %q", i, r.Glob)
+		case r.Regex != "":
+			if _, err := regexp.Compile(r.Regex); err != nil {
+				return fmt.Errorf("section_rules[%d]: %w", i, err)
+			}
+		}
+	}
+	for name, o := range c.Engines {
+		if o == nil {
+			continue
+		}
+		if o.MaxTurns < 0 {
+			return fmt.Errorf("engines.%s.max_turns must not be negative", name)
+		}
+		switch o.PromptDelivery {
+		case "", PromptDeliveryAuto, PromptDeliveryArgv, PromptDeliveryStdin, PromptDeliveryFile:
+		default:
+			return fmt.Errorf("engines.%s.prompt_delivery must be %q, %q, %q or %q", name,
+				PromptDeliveryAuto, PromptDeliveryArgv, PromptDeliveryStdin, PromptDeliveryFile)
+		}
+	}
+	return nil
+}
+
+// EngineOptions returns the options block for the named engine, or empty
+// options if there is none.
+func (c *Config) EngineOptions(name string) EngineOptions {
+	if o := c.Engines[name]; o != nil {
+		return *o
+	}
+	return EngineOptions{}
+}
+
+// RetryBackoff is the delay before the first retry of a transient engine
+// failure; it doubles on each further attempt.
+func (c *Config) RetryBackoff() time.Duration {
+	if c.RetryBackoffSeconds <= 0 {
+		return DefaultRetryBackoffSeconds * time.Second
+	}
+	return time.Duration(c.RetryBackoffSeconds) * time.Second
+}
+
+// RunTimeout is the deadline for a single engine run. A negative
+// timeout_minutes disables the deadline.
+func (c *Config) RunTimeout() time.Duration {
+	if c.TimeoutMinutes < 0 {
+		return 0
+	}
+	if c.TimeoutMinutes == 0 {
+		return DefaultTimeoutMinutes * time.Minute
+	}
+	return time.Duration(c.TimeoutMinutes) * time.Minute
+}
+
+// Workers is how many engine runs a split generation runs at once.
+func (c *Config) Workers() int {
+	if c.Concurrency < 1 {
+		return 1
+	}
+	return c.Concurrency
+}
+
+// DiffLimit is how many bytes of each changed file's diff an incremental
+// prompt includes. A negative diff_max_bytes leaves diffs out entirely.
+func (c *Config) DiffLimit() int {
+	if c.DiffMaxBytes < 0 {
+		return 0
+	}
+	if c.DiffMaxBytes == 0 {
+		return DefaultDiffMaxBytes
+	}
+	return c.DiffMaxBytes
+}
+
 func Save(gitRoot string, cfg *Config) error {
 	if err := os.MkdirAll(Dir(gitRoot), 0755); err != nil {
 		return fmt.Errorf("failed to create config dir: %w", err)
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func loadJSON(t *testing.T, data string) *Config {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(Dir(root), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(Path(root), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestLoadMigratesMaxRetries(t *testing.T) {
+	if cfg := loadJSON(t, `{"engine":"fake"}`); cfg.MaxRetries != DefaultMaxRetries {
+		t.Errorf("missing max_retries = %d, want %d", cfg.MaxRetries, DefaultMaxRetries)
+	}
+	if cfg := loadJSON(t, `{"engine":"fake","max_retries":0}`); cfg.MaxRetries != 0 {
+		t.Errorf("explicit max_retries 0 = %d, want 0", cfg.MaxRetries)
+	}
+	if cfg := loadJSON(t, `{"engine":"fake","max_retries":5}`); cfg.MaxRetries != 5 {
+		t.Errorf("max_retries 5 = %d, want 5", cfg.MaxRetries)
+	}
+}
+
+func TestValidateSectionRules(t *testing.T) {
+	tests := []struct {
+		rule SectionRule
+		ok   bool
+	}{
+		{SectionRule{Glob: "cmd/", Section: "CLI"}, true},
+		{SectionRule{Regex: `^internal/(git|wiki)/`, Section: "Internals", Weight: -2}, true},
+		{SectionRule{Glob: "cmd/"}, false},
+		{SectionRule{Section: "CLI"}, false},
+		{SectionRule{Glob: "cmd/", Regex: "cmd", Section: "CLI"}, false},
+		{SectionRule{Glob: "[cmd", Section: "CLI"}, false},
+		{SectionRule{Regex: "(cmd", Section: "CLI"}, false},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.SectionRules = []SectionRule{tt.rule}
+		if err := cfg.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate with %+v: err = %v, want ok %v", tt.rule, err, tt.ok)
+		}
+	}
+}
+
+func TestDetectionRules(t *testing.T) {
+	cfg := Default()
+	if got := len(cfg.DetectionRules()); got != len(DefaultSectionRules()) {
+		t.Errorf("default config has %d rules, want the %d default ones", got, len(DefaultSectionRules()))
+	}
+	cfg.Sections = []Section{{Title: "Overview"}}
+	if got := cfg.DetectionRules(); got != nil {
+		t.Errorf("custom outline without rules has rules %v", got)
+	}
+	cfg.SectionRules = []SectionRule{{Glob: "*.md", Section: "Overview"}}
+	if got := cfg.DetectionRules(); len(got) != 1 {
+		t.Errorf("configured rules = %v", got)
+	}
+}
@@ -3,6 +3,7 @@ package git
 import (
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -37,19 +38,171 @@ func CommitMessage(gitRoot string, hash string) (string, error) {
 	return run(gitRoot, "log", "-1", "--pretty=%B", hash)
 }
 
-func ChangedFilesInCommit(gitRoot string, hash string) ([]string, error) {
-	out, err := run(gitRoot, "diff-tree", "--no-commit-id", "--name-only", "-r", hash)
+// Statuses of a FileChange.
+const (
+	StatusAdded    = 'A'
+	StatusModified = 'M'
+	StatusDeleted  = 'D'
+	StatusRenamed  = 'R'
+)
+
+// FileChange is one file of a diff between two commits.
+type FileChange struct {
+	Status byte
+	Path   string
+	// OldPath is the path before a rename.
+	OldPath string
+}
+
+// ChangesInCommit returns the files a commit changed, with renames
+// detected.
+func ChangesInCommit(gitRoot string, hash string) ([]FileChange, error) {
+	out, err := run(gitRoot, "diff-tree", "--no-commit-id", "--name-status", "-z", "-M", "-r", "--root", hash)
 	if err != nil {
 		return nil, err
 	}
-	if out == "" {
-		return nil, nil
+	return parseNameStatus(out), nil
+}
+
+// ChangesSince returns the files changed between hash and HEAD, with
+// renames detected.
+func ChangesSince(gitRoot string, hash string) ([]FileChange, error) {
+	out, err := run(gitRoot, "diff", "--name-status", "-z", "-M", hash, "HEAD")
+	if err != nil {
+		return nil, err
 	}
-	return strings.Split(out, "\n"), nil
+	return parseNameStatus(out), nil
+}
+
+// parseNameStatus parses `--name-status -z` output. Copies count as
+// additions and type changes as modifications.
+func parseNameStatus(out string) []FileChange {
+	fields := strings.Split(strings.Trim(out, "\x00"), "\x00")
+	var changes []FileChange
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i]
+		if status == "" {
+			continue
+		}
+		c := FileChange{Status: status[0], Path: fields[i+1]}
+		switch c.Status {
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return changes
+			}
+			c.OldPath, c.Path = fields[i+1], fields[i+2]
+			i++
+			if c.Status == 'C' {
+				c.Status, c.OldPath = StatusAdded, ""
+			}
+		case 'T':
+			c.Status = StatusModified
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// FileDiff returns the unified diff of paths between two commits. An empty
+// from diffs the commit to against its parent. Pass the old and new path of
+// a renamed file to get the diff of its contents.
+func FileDiff(gitRoot string, from string, to string, paths ...string) (string, error) {
+	return run(gitRoot, diffArgs(from, to, paths)...)
+}
+
+func diffArgs(from string, to string, paths []string, extra ...string) []string {
+	var args []string
+	if from == "" {
+		args = append([]string{"diff-tree", "-p", "--root", "--no-commit-id", "-r"}, extra...)
+		args = append(args, "--no-color", "--no-ext-diff", "-M", to)
+	} else {
+		args = append([]string{"diff"}, extra...)
+		args = append(args, "--no-color", "--no-ext-diff", "-M", from, to)
+	}
+	return append(append(args, "--"), paths...)
 }
 
-func ChangedFilesSince(gitRoot string, hash string) ([]string, error) {
-	out, err := run(gitRoot, "diff", "--name-only", hash, "HEAD")
+// Hunk is one changed region of a diff: Lines lines from Start in the old
+// file became NewLines lines from NewStart in the new one. A pure insertion
+// has Lines 0 and Start the line it follows.
+type Hunk struct {
+	Start, Lines       int
+	NewStart, NewLines int
+}
+
+// FileHunks returns the changed regions of paths between two commits, like
+// FileDiff. Binary files have none.
+func FileHunks(gitRoot string, from string, to string, paths ...string) ([]Hunk, error) {
+	out, err := run(gitRoot, diffArgs(from, to, paths, "-U0")...)
+	if err != nil {
+		return nil, err
+	}
+	var hunks []Hunk
+	for _, line := range strings.Split(out, "\n") {
+		if h, ok := parseHunkHeader(line); ok {
+			hunks = append(hunks, h)
+		}
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader parses "@@ -start[,lines] +start[,lines] @@ ...".
+func parseHunkHeader(line string) (Hunk, bool) {
+	rest, ok := strings.CutPrefix(line, "@@ -")
+	if !ok {
+		return Hunk{}, false
+	}
+	oldPart, rest, ok := strings.Cut(rest, " +")
+	if !ok {
+		return Hunk{}, false
+	}
+	newPart, _, ok := strings.Cut(rest, " @@")
+	if !ok {
+		return Hunk{}, false
+	}
+	var h Hunk
+	if h.Start, h.Lines, ok = parseHunkRange(oldPart); !ok {
+		return Hunk{}, false
+	}
+	if h.NewStart, h.NewLines, ok = parseHunkRange(newPart); !ok {
+		return Hunk{}, false
+	}
+	return h, true
+}
+
+func parseHunkRange(s string) (start int, lines int, ok bool) {
+	startStr, linesStr, found := strings.Cut(s, ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	lines = 1
+	if found {
+		if lines, err = strconv.Atoi(linesStr); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, lines, true
+}
+
+// FileAt returns the contents of path at rev.
+func FileAt(gitRoot string, rev string, path string) ([]byte, error) {
+	cmd := exec.Command("git", "show", rev+":"+path)
+	cmd.Dir = gitRoot
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show %s:%s: %w", rev, path, err)
+	}
+	return out, nil
+}
+
+// CommitsBetween returns the commits reachable from to but not from, oldest
+// first. An empty from returns just to.
+func CommitsBetween(gitRoot string, from string, to string) ([]string, error) {
+	if from == "" {
+		return []string{to}, nil
+	}
+	out, err := run(gitRoot, "rev-list", "--reverse", from+".."+to)
 	if err != nil {
 		return nil, err
 	}
@@ -77,3 +230,66 @@ func HasChanges(gitRoot string, path string) (bool, error) {
 	}
 	return out != "", nil
 }
+
+// ListFiles returns all files tracked in the index.
+func ListFiles(gitRoot string) ([]string, error) {
+	out, err := run(gitRoot, "ls-files")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// DirtyPaths returns every path that differs from HEAD in the index or
+// working tree, including untracked files. For renames both the old and the
+// new path are reported.
+func DirtyPaths(gitRoot string) ([]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	cmd.Dir = gitRoot
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status: %w", err)
+	}
+	var paths []string
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+		paths = append(paths, entry[3:])
+		// Renames and copies are followed by the original path.
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+			if i < len(fields) && fields[i] != "" {
+				paths = append(paths, fields[i])
+			}
+		}
+	}
+	return paths, nil
+}
+
+// IsTracked reports whether path exists in HEAD.
+func IsTracked(gitRoot string, path string) bool {
+	_, err := run(gitRoot, "cat-file", "-e", "HEAD:"+path)
+	return err == nil
+}
+
+// RestoreFromHead resets path in both the index and the working tree to its
+// content in HEAD.
+func RestoreFromHead(gitRoot string, path string) error {
+	_, err := run(gitRoot, "checkout", "HEAD", "--", path)
+	return err
+}
+
+// ConfigValue returns a git config value, or "" if it is unset.
+func ConfigValue(gitRoot string, key string) string {
+	out, err := run(gitRoot, "config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package git
+
+import "testing"
+
+func TestParseHunkHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		want Hunk
+		ok   bool
+	}{
+		{"@@ -10,5 +12,7 @@ func main() {", Hunk{Start: 10, Lines: 5, NewStart: 12, NewLines: 7}, true},
+		{"@@ -3 +3 @@", Hunk{Start: 3, Lines: 1, NewStart: 3, NewLines: 1}, true},
+		{"@@ -20,0 +21,4 @@", Hunk{Start: 20, Lines: 0, NewStart: 21, NewLines: 4}, true},
+		{"@@ -1,3 +0,0 @@", Hunk{Start: 1, Lines: 3, NewStart: 0, NewLines: 0}, true},
+		{"@@@ -1,2 -1,2 +1,3 @@@", Hunk{}, false},
+		{"@@ -a,2 +1,2 @@", Hunk{}, false},
+		{"@@ -1,2 +1,x @@", Hunk{}, false},
+		{"@@ -1,2 +1,2", Hunk{}, false},
+		{"--- a/main.go", Hunk{}, false},
+		{"", Hunk{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseHunkHeader(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseHunkHeader(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	out := "M\x00main.go\x00" +
+		"A\x00internal/new.go\x00" +
+		"D\x00old.go\x00" +
+		"R087\x00internal/a.go\x00internal/pkg/a.go\x00" +
+		"C100\x00tmpl.go\x00tmpl_copy.go\x00" +
+		"T\x00link\x00" +
+		"M\x00name with spaces.go\x00"
+	want := []FileChange{
+		{Status: StatusModified, Path: "main.go"},
+		{Status: StatusAdded, Path: "internal/new.go"},
+		{Status: StatusDeleted, Path: "old.go"},
+		{Status: StatusRenamed, Path: "internal/pkg/a.go", OldPath: "internal/a.go"},
+		{Status: StatusAdded, Path: "tmpl_copy.go"},
+		{Status: StatusModified, Path: "link"},
+		{Status: StatusModified, Path: "name with spaces.go"},
+	}
+	got := parseNameStatus(out)
+	if len(got) != len(want) {
+		t.Fatalf("parseNameStatus = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for _, out := range []string{"", "\x00", "R100\x00only-old.go\x00"} {
+		if got := parseNameStatus(out); len(got) != 0 {
+			t.Errorf("parseNameStatus(%q) = %+v, want none", out, got)
+		}
+	}
+}
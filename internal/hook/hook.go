@@ -16,6 +16,11 @@ func hookPath(gitRoot string) string {
 	return filepath.Join(gitRoot, ".git", "hooks", "post-commit")
 }
 
+// Path is the post-commit hook file repowiki installs into.
+func Path(gitRoot string) string {
+	return hookPath(gitRoot)
+}
+
 // Script generates the hook script using the absolute path to the repowiki binary.
 func Script(binaryPath string) string {
 	return markerStart + `
@@ -82,6 +87,20 @@ func IsInstalled(gitRoot string) bool {
 	return strings.Contains(string(data), markerStart)
 }
 
+// BinaryPath returns the repowiki binary path recorded in the installed hook.
+func BinaryPath(gitRoot string) (string, bool) {
+	data, err := os.ReadFile(hookPath(gitRoot))
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "REPOWIKI_BIN="); ok {
+			return strings.Trim(v, `"`), true
+		}
+	}
+	return "", false
+}
+
 func removeBlock(content string) string {
 	startIdx := strings.Index(content, markerStart)
 	endIdx := strings.Index(content, markerEnd)
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// --- Anthropic Messages API ---
+
+const anthropicVersion = "2023-06-01"
+
+// defaultAnthropicMaxTokens is used when the request leaves MaxTokens unset;
+// the Messages API requires it.
+const defaultAnthropicMaxTokens = 8192
+
+type anthropicClient struct {
+	baseURL string
+	apiKey  string
+	hc      *http.Client
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (c *anthropicClient) Chat(ctx context.Context, req *Request) (*Response, error) {
+	body := anthropicRequest{Model: req.Model, MaxTokens: req.MaxTokens, System: req.System}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = defaultAnthropicMaxTokens
+	}
+	for _, t := range req.Turns {
+		body.Messages = append(body.Messages, anthropicMessageFor(t))
+	}
+	for _, tool := range req.Tools {
+		body.Tools = append(body.Tools, anthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: tool.Schema})
+	}
+
+	headers := map[string]string{"anthropic-version": anthropicVersion}
+	if c.apiKey != "" {
+		headers["x-api-key"] = c.apiKey
+	}
+	var out anthropicResponse
+	if err := postJSON(ctx, c.hc, c.baseURL+"/v1/messages", headers, body, &out); err != nil {
+		return nil, err
+	}
+
+	resp := &Response{
+		StopReason: out.StopReason,
+		Usage:      Usage{InputTokens: out.Usage.InputTokens, OutputTokens: out.Usage.OutputTokens},
+	}
+	var text []string
+	for _, b := range out.Content {
+		switch b.Type {
+		case "text":
+			text = append(text, b.Text)
+		case "tool_use":
+			resp.Calls = append(resp.Calls, ToolCall{ID: b.ID, Name: b.Name, Args: b.Input})
+		}
+	}
+	resp.Text = strings.Join(text, "\n")
+	return resp, nil
+}
+
+func anthropicMessageFor(t Turn) anthropicMessage {
+	m := anthropicMessage{Role: t.Role}
+	for _, r := range t.Results {
+		m.Content = append(m.Content, anthropicBlock{Type: "tool_result", ToolUseID: r.CallID, Content: r.Content, IsError: r.IsError})
+	}
+	if t.Text != "" {
+		m.Content = append(m.Content, anthropicBlock{Type: "text", Text: t.Text})
+	}
+	for _, call := range t.Calls {
+		m.Content = append(m.Content, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: call.Args})
+	}
+	return m
+}
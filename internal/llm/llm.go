@@ -0,0 +1,165 @@
+// Package llm is a minimal chat client for OpenAI-compatible and Anthropic
+// Messages HTTP APIs, with just enough tool-calling support for repowiki's
+// built-in agent loop.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// Tool describes a function the model may call.
+type Tool struct {
+	Name        string
+	Description string
+	// Schema is the JSON schema of the tool's arguments object.
+	Schema map[string]any
+}
+
+// ToolCall is a tool invocation requested by the model.
+type ToolCall struct {
+	ID   string
+	Name string
+	Args json.RawMessage
+}
+
+// ToolResult answers a ToolCall.
+type ToolResult struct {
+	CallID  string
+	Content string
+	IsError bool
+}
+
+// Turn is one message in the conversation. User turns carry Text and/or
+// Results; assistant turns carry Text and/or Calls.
+type Turn struct {
+	Role    string // "user" or "assistant"
+	Text    string
+	Calls   []ToolCall
+	Results []ToolResult
+}
+
+// Request is a single chat completion request.
+type Request struct {
+	Model     string
+	System    string
+	Turns     []Turn
+	Tools     []Tool
+	MaxTokens int
+	// ContextSize asks local servers for a context window of this many
+	// tokens; hosted APIs ignore it.
+	ContextSize int
+}
+
+// Usage is the token accounting reported by the API.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// Response is the model's reply to a Request.
+type Response struct {
+	Text       string
+	Calls      []ToolCall
+	StopReason string
+	Usage      Usage
+}
+
+// Client sends chat requests to one provider.
+type Client interface {
+	Chat(ctx context.Context, req *Request) (*Response, error)
+}
+
+// StatusError is returned when the API answers with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// New returns a client for the given provider. An empty baseURL selects the
+// provider's public endpoint.
+func New(provider string, baseURL string, apiKey string, hc *http.Client) (Client, error) {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	switch provider {
+	case ProviderOpenAI:
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		return &openAIClient{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, hc: hc}, nil
+	case ProviderAnthropic:
+		if baseURL == "" {
+			baseURL = "https://api.anthropic.com"
+		}
+		return &anthropicClient{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, hc: hc}, nil
+	case ProviderOllama:
+		if baseURL == "" {
+			baseURL = "http://localhost:11434"
+		}
+		return &ollamaClient{baseURL: strings.TrimRight(baseURL, "/"), hc: hc}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (valid: %s, %s, %s)", provider, ProviderOpenAI, ProviderAnthropic, ProviderOllama)
+	}
+}
+
+// postJSON sends body as JSON to url and decodes the response into out.
+func postJSON(ctx context.Context, hc *http.Client, url string, headers map[string]string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return &DecodeError{Err: err}
+	}
+	return nil
+}
+
+var errNoChoices = errors.New("response contains no choices")
+
+// DecodeError is returned when the API response is not the expected JSON.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string { return "malformed response: " + e.Err.Error() }
+
+func (e *DecodeError) Unwrap() error { return e.Err }
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+)
+
+// --- Ollama native chat API ---
+//
+// Only plain chat is supported; Tools are ignored. Request.ContextSize is
+// passed as num_ctx because Ollama otherwise truncates long prompts to its
+// small default window.
+
+type ollamaClient struct {
+	baseURL string
+	hc      *http.Client
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+func (c *ollamaClient) Chat(ctx context.Context, req *Request) (*Response, error) {
+	body := ollamaRequest{Model: req.Model}
+	if req.System != "" {
+		body.Messages = append(body.Messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, t := range req.Turns {
+		body.Messages = append(body.Messages, ollamaMessage{Role: t.Role, Content: t.Text})
+	}
+	opts := map[string]any{}
+	if req.ContextSize > 0 {
+		opts["num_ctx"] = req.ContextSize
+	}
+	if req.MaxTokens > 0 {
+		opts["num_predict"] = req.MaxTokens
+	}
+	if len(opts) > 0 {
+		body.Options = opts
+	}
+
+	var out ollamaResponse
+	if err := postJSON(ctx, c.hc, c.baseURL+"/api/chat", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &Response{
+		Text:       out.Message.Content,
+		StopReason: out.DoneReason,
+		Usage:      Usage{InputTokens: out.PromptEvalCount, OutputTokens: out.EvalCount},
+	}, nil
+}
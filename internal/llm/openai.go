@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// --- OpenAI-compatible chat completions ---
+
+type openAIClient struct {
+	baseURL string
+	apiKey  string
+	hc      *http.Client
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model     string          `json:"model"`
+	Messages  []openAIMessage `json:"messages"`
+	Tools     []openAITool    `json:"tools,omitempty"`
+	MaxTokens int             `json:"max_tokens,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (c *openAIClient) Chat(ctx context.Context, req *Request) (*Response, error) {
+	body := openAIRequest{Model: req.Model, MaxTokens: req.MaxTokens}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: strPtr(req.System)})
+	}
+	for _, t := range req.Turns {
+		body.Messages = append(body.Messages, openAIMessages(t)...)
+	}
+	for _, tool := range req.Tools {
+		var ot openAITool
+		ot.Type = "function"
+		ot.Function.Name = tool.Name
+		ot.Function.Description = tool.Description
+		ot.Function.Parameters = tool.Schema
+		body.Tools = append(body.Tools, ot)
+	}
+
+	headers := map[string]string{}
+	if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+	var out openAIResponse
+	if err := postJSON(ctx, c.hc, c.baseURL+"/chat/completions", headers, body, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Choices) == 0 {
+		return nil, &DecodeError{Err: errNoChoices}
+	}
+
+	choice := out.Choices[0]
+	resp := &Response{
+		StopReason: choice.FinishReason,
+		Usage:      Usage{InputTokens: out.Usage.PromptTokens, OutputTokens: out.Usage.CompletionTokens},
+	}
+	if choice.Message.Content != nil {
+		resp.Text = *choice.Message.Content
+	}
+	for _, tc := range choice.Message.ToolCalls {
+		args := json.RawMessage(tc.Function.Arguments)
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		resp.Calls = append(resp.Calls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Args: args})
+	}
+	return resp, nil
+}
+
+// openAIMessages converts a turn into chat messages; tool results become
+// one "tool" message each.
+func openAIMessages(t Turn) []openAIMessage {
+	var msgs []openAIMessage
+	for _, r := range t.Results {
+		msgs = append(msgs, openAIMessage{Role: "tool", ToolCallID: r.CallID, Content: strPtr(r.Content)})
+	}
+	if t.Text == "" && len(t.Calls) == 0 {
+		return msgs
+	}
+	m := openAIMessage{Role: t.Role}
+	if t.Text != "" {
+		m.Content = strPtr(t.Text)
+	}
+	for _, call := range t.Calls {
+		var tc openAIToolCall
+		tc.ID = call.ID
+		tc.Type = "function"
+		tc.Function.Name = call.Name
+		tc.Function.Arguments = string(call.Args)
+		m.ToolCalls = append(m.ToolCalls, tc)
+	}
+	return append(msgs, m)
+}
+
+func strPtr(s string) *string { return &s }
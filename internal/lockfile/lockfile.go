@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -15,6 +16,11 @@ func lockPath(gitRoot string) string {
 	return filepath.Join(gitRoot, ".repowiki", lockFileName)
 }
 
+// Path is the lock file location.
+func Path(gitRoot string) string {
+	return lockPath(gitRoot)
+}
+
 func Acquire(gitRoot string) error {
 	lp := lockPath(gitRoot)
 
@@ -53,6 +59,14 @@ func IsLocked(gitRoot string) bool {
 	return err == nil
 }
 
+// IsStale reports whether an existing lock belongs to a process that is no
+// longer running; Acquire removes such locks. A live owner keeps its lock
+// however long the run takes, since retries, fallbacks and split
+// generation can far exceed a single engine timeout.
+func IsStale(gitRoot string) bool {
+	return isStale(lockPath(gitRoot))
+}
+
 func isStale(lp string) bool {
 	data, err := os.ReadFile(lp)
 	if err != nil {
@@ -60,10 +74,6 @@ func isStale(lp string) bool {
 	}
 
 	lines := strings.SplitN(string(data), "\n", 3)
-	if len(lines) < 1 {
-		return true
-	}
-
 	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
 	if err != nil {
 		return true
@@ -76,18 +86,5 @@ func isStale(lp string) bool {
 	}
 
 	// On Unix, FindProcess always succeeds. Send signal 0 to check.
-	err = proc.Signal(os.Signal(nil))
-	if err != nil {
-		return true // Process not running
-	}
-
-	// Check age - stale if older than 30 minutes
-	if len(lines) >= 2 {
-		ts, err := time.Parse(time.RFC3339, strings.TrimSpace(lines[1]))
-		if err == nil && time.Since(ts) > 30*time.Minute {
-			return true
-		}
-	}
-
-	return false
+	return proc.Signal(syscall.Signal(0)) != nil
 }
@@ -0,0 +1,52 @@
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeLock(t *testing.T, root string, pid int, age time.Duration) {
+	t.Helper()
+	lp := lockPath(root)
+	if err := os.MkdirAll(filepath.Dir(lp), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stamp := time.Now().Add(-age).UTC().Format(time.RFC3339)
+	if err := os.WriteFile(lp, []byte(fmt.Sprintf("%d\n%s\n", pid, stamp)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLiveOwnerKeepsOldLock(t *testing.T) {
+	root := t.TempDir()
+	writeLock(t, root, os.Getpid(), 3*time.Hour)
+	if IsStale(root) {
+		t.Fatal("lock of a running process reported stale")
+	}
+	if err := Acquire(root); err == nil {
+		t.Fatal("Acquire took a lock held by a running process")
+	}
+}
+
+func TestDeadOwnerLockIsStale(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("cannot run true:", err)
+	}
+	root := t.TempDir()
+	writeLock(t, root, cmd.Process.Pid, time.Minute)
+	if !IsStale(root) {
+		t.Fatal("lock of an exited process not reported stale")
+	}
+	if err := Acquire(root); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	Release(root)
+	if IsLocked(root) {
+		t.Fatal("lock left after Release")
+	}
+}
@@ -0,0 +1,93 @@
+// Package pathmatch implements gitignore-style glob matching on
+// slash-separated repository paths.
+package pathmatch
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches the gitignore-style pattern:
+//
+//   - "*" and "?" match within a single path segment, "[...]" is a
+//     character class, as in path.Match
+//   - "**" matches zero or more whole segments
+//   - a pattern without a slash (other than a trailing one) matches at any
+//     depth, e.g. "*.go" or "config"
+//   - a leading "/" anchors the pattern to the repository root
+//   - a trailing "/" only matches directories, i.e. parents of name
+//
+// A pattern that matches a directory also matches everything below it.
+func Match(pattern string, name string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	if strings.HasPrefix(pattern, "/") {
+		pattern = pattern[1:]
+	} else if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+
+	patSegs := strings.Split(pattern, "/")
+	nameSegs := strings.Split(strings.Trim(name, "/"), "/")
+
+	// Try the full path and each parent directory.
+	for n := len(nameSegs); n >= 1; n-- {
+		if dirOnly && n == len(nameSegs) {
+			continue
+		}
+		if matchSegments(patSegs, nameSegs[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAny reports whether name matches at least one of the patterns.
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid reports whether pattern is syntactically valid.
+func Valid(pattern string) bool {
+	for _, seg := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func matchSegments(pat []string, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
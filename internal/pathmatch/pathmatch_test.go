@@ -0,0 +1,51 @@
+package pathmatch
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "internal/wiki/detect.go", true},
+		{"*.go", "internal/wiki/detect.go.bak", false},
+		{"config", "internal/config/config.go", true},
+		{"internal/", "internal/wiki/detect.go", true},
+		{"internal/", "internal", false},
+		{"wiki/", "internal/wiki/detect.go", true},
+		{"/wiki/", "internal/wiki/detect.go", false},
+		{"/cmd/*/main.go", "cmd/repowiki/main.go", true},
+		{"/cmd/*/main.go", "tools/cmd/x/main.go", false},
+		{"internal/*.go", "internal/wiki/detect.go", false},
+		{"internal/**/*.go", "internal/wiki/detect.go", true},
+		{"internal/**/*.go", "internal/main.go", true},
+		{"**/prompts/*.tmpl", "internal/wiki/prompts/full.tmpl", true},
+		{"internal/wiki", "internal/wiki/prompts/full.tmpl", true},
+		{"docs/?.md", "docs/a.md", true},
+		{"docs/?.md", "docs/ab.md", false},
+		{"[a-c]*.go", "b_test.go", true},
+		{"[a-c]*.go", "d.go", false},
+		{"", "main.go", false},
+		{"  *.md  ", "README.md", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, p := range []string{"*.go", "internal/**/", "/cmd/*/main.go", "[a-z]*"} {
+		if !Valid(p) {
+			t.Errorf("Valid(%q) = false, want true", p)
+		}
+	}
+	for _, p := range []string{"[a-", "internal/[", `bad\`} {
+		if Valid(p) {
+			t.Errorf("Valid(%q) = true, want false", p)
+		}
+	}
+}
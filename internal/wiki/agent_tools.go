@@ -0,0 +1,318 @@
+package wiki
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/llm"
+	"github.com/ikrasnodymov/repowiki/internal/pathmatch"
+)
+
+// Limits keep tool results small enough for a model's context window.
+const (
+	maxReadBytes   = 100 * 1024
+	maxListEntries = 500
+	maxGrepMatches = 200
+)
+
+// agentToolNames are the tools built-in engines can offer, in order.
+var agentToolNames = []string{"read_file", "list_files", "grep", "write_file"}
+
+// agentTools implements the tools offered to models by built-in engines.
+// Reads are confined to the repository; writes to the wiki directory.
+type agentTools struct {
+	root     string
+	wikiPath string
+	excluded []string
+	// allowed restricts the offered tools (the engine's allowed_tools
+	// option); empty offers all of them.
+	allowed []string
+}
+
+func newAgentTools(cfg *config.Config, gitRoot string) *agentTools {
+	return &agentTools{
+		root:     gitRoot,
+		wikiPath: filepath.ToSlash(filepath.Clean(cfg.WikiPath)),
+		excluded: cfg.ExcludedPaths,
+		allowed:  cfg.EngineOptions(cfg.Engine).AllowedTools,
+	}
+}
+
+func (t *agentTools) enabled(name string) bool {
+	return len(t.allowed) == 0 || containsString(t.allowed, name)
+}
+
+func (t *agentTools) definitions() []llm.Tool {
+	str := map[string]any{"type": "string"}
+	obj := func(required []string, props map[string]any) map[string]any {
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	}
+	all := []llm.Tool{
+		{
+			Name:        "read_file",
+			Description: "Read a file from the repository. Paths are relative to the repository root.",
+			Schema:      obj([]string{"path"}, map[string]any{"path": str}),
+		},
+		{
+			Name:        "list_files",
+			Description: "List repository files matching a gitignore-style glob such as \"**/*.go\" or \"internal/\". An empty pattern lists everything.",
+			Schema:      obj(nil, map[string]any{"pattern": str}),
+		},
+		{
+			Name:        "grep",
+			Description: "Search file contents with a regular expression. Optionally restrict to files matching a glob.",
+			Schema:      obj([]string{"regex"}, map[string]any{"regex": str, "glob": str}),
+		},
+		{
+			Name:        "write_file",
+			Description: fmt.Sprintf("Create or overwrite a file. Only paths inside %s/ may be written.", t.wikiPath),
+			Schema:      obj([]string{"path", "content"}, map[string]any{"path": str, "content": str}),
+		},
+	}
+	var tools []llm.Tool
+	for _, tool := range all {
+		if t.enabled(tool.Name) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// call executes one tool call. Tool failures are reported back to the model
+// as error results rather than aborting the run.
+func (t *agentTools) call(c llm.ToolCall) llm.ToolResult {
+	var args struct {
+		Path    string `json:"path"`
+		Pattern string `json:"pattern"`
+		Regex   string `json:"regex"`
+		Glob    string `json:"glob"`
+		Content string `json:"content"`
+	}
+	var out string
+	err := json.Unmarshal(c.Args, &args)
+	if err == nil && !t.enabled(c.Name) {
+		err = fmt.Errorf("tool %q is not allowed", c.Name)
+	} else if err == nil {
+		switch c.Name {
+		case "read_file":
+			out, err = t.readFile(args.Path)
+		case "list_files":
+			out, err = t.listFiles(args.Pattern)
+		case "grep":
+			out, err = t.grep(args.Regex, args.Glob)
+		case "write_file":
+			out, err = t.writeFile(args.Path, args.Content)
+		default:
+			err = fmt.Errorf("unknown tool %q", c.Name)
+		}
+	}
+	if err != nil {
+		return llm.ToolResult{CallID: c.ID, Content: "error: " + err.Error(), IsError: true}
+	}
+	return llm.ToolResult{CallID: c.ID, Content: out}
+}
+
+// resolve cleans a model-supplied path and rejects anything outside the
+// repository or inside .git, including paths that only leave it through a
+// symlink. The result is the path of the file the symlinks lead to.
+func (t *agentTools) resolve(p string) (string, error) {
+	rel := filepath.ToSlash(filepath.Clean(strings.TrimPrefix(strings.TrimSpace(p), "./")))
+	if rel == "" || rel == "." || strings.HasPrefix(rel, "/") || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("path %q is outside the repository", p)
+	}
+	if rel == ".git" || strings.HasPrefix(rel, ".git/") {
+		return "", fmt.Errorf("path %q is not accessible", p)
+	}
+	real, ok := t.realPath(rel)
+	if !ok {
+		return "", fmt.Errorf("path %q is outside the repository", p)
+	}
+	if real == ".git" || strings.HasPrefix(real, ".git/") {
+		return "", fmt.Errorf("path %q is not accessible", p)
+	}
+	return real, nil
+}
+
+// realPath follows the symlinks of rel, or of its deepest existing parent
+// when rel doesn't exist yet, and returns where it leads relative to the
+// repository root. ok is false when that is outside the repository or rel
+// is a dangling symlink, which a write would follow anywhere.
+func (t *agentTools) realPath(rel string) (string, bool) {
+	root, err := filepath.EvalSymlinks(t.root)
+	if err != nil {
+		return "", false
+	}
+	p := filepath.Join(root, rel)
+	var missing []string
+	for {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			r, err := filepath.Rel(root, filepath.Join(append([]string{real}, missing...)...))
+			r = filepath.ToSlash(r)
+			if err != nil || r == "." || r == ".." || strings.HasPrefix(r, "../") {
+				return "", false
+			}
+			return r, true
+		}
+		if _, lerr := os.Lstat(p); lerr == nil || !os.IsNotExist(err) {
+			return "", false
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", false
+		}
+		missing = append([]string{filepath.Base(p)}, missing...)
+		p = parent
+	}
+}
+
+func (t *agentTools) inWiki(rel string) bool {
+	return rel == t.wikiPath || strings.HasPrefix(rel, t.wikiPath+"/")
+}
+
+func (t *agentTools) readFile(p string) (string, error) {
+	rel, err := t.resolve(p)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(t.root, rel))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxReadBytes {
+		return string(data[:maxReadBytes]) + "\n[truncated]", nil
+	}
+	return string(data), nil
+}
+
+// walk visits repository files, skipping .git and excluded paths except the
+// wiki itself, which models need to read when updating it.
+func (t *agentTools) walk(fn func(rel string) bool) error {
+	return filepath.WalkDir(t.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(t.root, path)
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if rel == ".git" || (!t.inWiki(rel) && !strings.HasPrefix(t.wikiPath, rel+"/") && isExcluded(rel+"/", t.excluded)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !t.inWiki(rel) && isExcluded(rel, t.excluded) {
+			return nil
+		}
+		if !fn(rel) {
+			return fs.SkipAll
+		}
+		return nil
+	})
+}
+
+func (t *agentTools) listFiles(pattern string) (string, error) {
+	if pattern != "" && !pathmatch.Valid(pattern) {
+		return "", fmt.Errorf("invalid glob %q", pattern)
+	}
+	var files []string
+	truncated := false
+	err := t.walk(func(rel string) bool {
+		if pattern == "" || pathmatch.Match(pattern, rel) {
+			if len(files) == maxListEntries {
+				truncated = true
+				return false
+			}
+			files = append(files, rel)
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "no matching files", nil
+	}
+	out := strings.Join(files, "\n")
+	if truncated {
+		out += "\n[truncated]"
+	}
+	return out, nil
+}
+
+func (t *agentTools) grep(expr string, glob string) (string, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	err = t.walk(func(rel string) bool {
+		if glob != "" && !pathmatch.Match(glob, rel) {
+			return true
+		}
+		real, err := t.resolve(rel)
+		if err != nil {
+			return true
+		}
+		f, err := os.Open(filepath.Join(t.root, real))
+		if err != nil {
+			return true
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for n := 1; sc.Scan(); n++ {
+			if re.MatchString(sc.Text()) {
+				matches = append(matches, fmt.Sprintf("%s:%d: %s", rel, n, sc.Text()))
+				if len(matches) >= maxGrepMatches {
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "no matches", nil
+	}
+	return strings.Join(matches, "\n"), nil
+}
+
+func (t *agentTools) writeFile(p string, content string) (string, error) {
+	rel, err := t.resolve(p)
+	if err != nil {
+		return "", err
+	}
+	if !t.inWiki(rel) || rel == t.wikiPath {
+		return "", fmt.Errorf("writes are only allowed inside %s/", t.wikiPath)
+	}
+	full := filepath.Join(t.root, rel)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("wrote %d bytes to %s", len(content), rel), nil
+}
+
+// isExcluded reports whether path starts with any excluded prefix, matching
+// the prefix semantics of excluded_paths.
+func isExcluded(path string, excluded []string) bool {
+	for _, ex := range excluded {
+		if strings.HasPrefix(path, ex) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package wiki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/llm"
+)
+
+// stubChat serves OpenAI-style chat completions, answering the nth request
+// with replies[n] (the last one repeats) and recording the requests.
+type stubChat struct {
+	replies  []string
+	requests []map[string]any
+}
+
+func (s *stubChat) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	s.requests = append(s.requests, body)
+	reply := s.replies[min(len(s.requests), len(s.replies))-1]
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, reply)
+}
+
+func toolCallReply(calls ...string) string {
+	return fmt.Sprintf(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[%s]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":100,"completion_tokens":10}}`, strings.Join(calls, ","))
+}
+
+func toolCall(id string, name string, args map[string]string) string {
+	a, _ := json.Marshal(args)
+	c, _ := json.Marshal(map[string]any{"id": id, "type": "function", "function": map[string]string{"name": name, "arguments": string(a)}})
+	return string(c)
+}
+
+func textReply(text string) string {
+	return fmt.Sprintf(`{"choices":[{"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":{"prompt_tokens":200,"completion_tokens":20}}`, text)
+}
+
+func newStubInvocation(t *testing.T, stub *stubChat) (llm.Client, *Invocation) {
+	t.Helper()
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	client, err := llm.New(llm.ProviderOpenAI, srv.URL, "", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Engine = config.EngineAPI
+	cfg.Model = "stub"
+	cfg.MaxTurns = 3
+	return client, &Invocation{Cfg: cfg, GitRoot: root, Request: Request{Prompt: "document the repo"}}
+}
+
+func TestAgentLoopRunsTools(t *testing.T) {
+	stub := &stubChat{replies: []string{
+		toolCallReply(
+			toolCall("c1", "read_file", map[string]string{"path": "main.go"}),
+			toolCall("c2", "write_file", map[string]string{"path": ".qoder/repowiki/en/content/Overview.md", "content": "# Overview\n"}),
+			toolCall("c3", "write_file", map[string]string{"path": "main.go", "content": "hijacked"}),
+		),
+		textReply("done"),
+	}}
+	client, inv := newStubInvocation(t, stub)
+
+	res, err := runAgentLoop(context.Background(), client, inv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Output != "done" {
+		t.Errorf("Output = %q, want %q", res.Output, "done")
+	}
+	if res.Usage.InputTokens != 300 || res.Usage.OutputTokens != 30 || res.Usage.Turns != 2 {
+		t.Errorf("Usage = %+v, want 300 in, 30 out, 2 turns", res.Usage)
+	}
+	page, err := os.ReadFile(filepath.Join(inv.GitRoot, ".qoder/repowiki/en/content/Overview.md"))
+	if err != nil || string(page) != "# Overview\n" {
+		t.Errorf("wiki page = %q, %v", page, err)
+	}
+	if src, _ := os.ReadFile(filepath.Join(inv.GitRoot, "main.go")); string(src) != "package main\n" {
+		t.Errorf("write outside the wiki was allowed: main.go = %q", src)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(stub.requests))
+	}
+	results := map[string]string{}
+	for _, m := range stub.requests[1]["messages"].([]any) {
+		msg := m.(map[string]any)
+		if msg["role"] == "tool" {
+			results[msg["tool_call_id"].(string)] = msg["content"].(string)
+		}
+	}
+	if results["c1"] != "package main\n" {
+		t.Errorf("read_file result = %q", results["c1"])
+	}
+	if !strings.HasPrefix(results["c2"], "wrote ") {
+		t.Errorf("write_file result = %q", results["c2"])
+	}
+	if !strings.HasPrefix(results["c3"], "error: ") {
+		t.Errorf("write_file outside the wiki = %q, want an error", results["c3"])
+	}
+}
+
+func TestAgentLoopFailsAtMaxTurns(t *testing.T) {
+	stub := &stubChat{replies: []string{
+		toolCallReply(toolCall("c1", "list_files", map[string]string{"pattern": "**/*.go"})),
+	}}
+	client, inv := newStubInvocation(t, stub)
+
+	res, err := runAgentLoop(context.Background(), client, inv, 0)
+	if err == nil {
+		t.Fatalf("got result %+v, want an error", res)
+	}
+	if kind := ErrorKindOf(err); kind != ErrExit {
+		t.Errorf("error kind = %q, want %q", kind, ErrExit)
+	}
+	if len(stub.requests) != inv.Cfg.MaxTurns {
+		t.Errorf("got %d requests, want %d", len(stub.requests), inv.Cfg.MaxTurns)
+	}
+}
+
+func TestResolveConfinesSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	cfg := config.Default()
+	wiki := filepath.Join(root, cfg.WikiPath)
+	for _, dir := range []string{wiki, filepath.Join(root, "src"), filepath.Join(root, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"escape":             outside,
+		"src/secret":         filepath.Join(outside, "secret.txt"),
+		"dangling":           filepath.Join(outside, "missing.txt"),
+		"gitdir":             filepath.Join(root, ".git"),
+		"internal":           filepath.Join(root, "src"),
+		cfg.WikiPath + "/up": filepath.Join(root, "src"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skip("symlinks unavailable:", err)
+		}
+	}
+	tools := newAgentTools(cfg, root)
+
+	tests := []struct {
+		path string
+		want string // "" means rejected
+	}{
+		{"main.go", "main.go"},
+		{"./src/new/file.go", "src/new/file.go"},
+		{"internal/x.go", "src/x.go"},
+		{"../x", ""},
+		{"/etc/passwd", ""},
+		{".git/config", ""},
+		{"gitdir/config", ""},
+		{"escape/x", ""},
+		{"escape/new/dir/x", ""},
+		{"src/secret", ""},
+		{"dangling", ""},
+	}
+	for _, tt := range tests {
+		got, err := tools.resolve(tt.path)
+		if tt.want == "" && err == nil {
+			t.Errorf("resolve(%q) = %q, want an error", tt.path, got)
+		}
+		if tt.want != "" && (err != nil || got != tt.want) {
+			t.Errorf("resolve(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	if _, err := tools.writeFile(cfg.WikiPath+"/up/x.go", "x"); err == nil {
+		t.Error("write through a wiki symlink into src/ was allowed")
+	}
+	if _, err := tools.writeFile(cfg.WikiPath+"/en/content/A.md", "a"); err != nil {
+		t.Errorf("write inside the wiki: %v", err)
+	}
+}
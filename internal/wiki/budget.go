@@ -0,0 +1,148 @@
+package wiki
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// ErrBudgetExhausted is returned (wrapped) when the daily or monthly budget
+// has been used up, so no engine run is started.
+var ErrBudgetExhausted = errors.New("budget exhausted")
+
+// errRunBudget is the cancellation cause of a run stopped by the meter.
+var errRunBudget = errors.New("per-run token budget exceeded")
+
+// Spend is the usage recorded over a period.
+type Spend struct {
+	Tokens  int
+	CostUSD float64
+}
+
+// Tokens is the amount counted against token budgets.
+func (u Usage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Spending sums the recorded runs of the current UTC day and month.
+func Spending(gitRoot string, now time.Time) (day, month Spend, err error) {
+	records, err := LoadRunRecords(gitRoot)
+	if err != nil {
+		return day, month, err
+	}
+	today := now.UTC().Format("2006-01-02")
+	thisMonth := today[:7]
+	for _, r := range records {
+		if !strings.HasPrefix(r.Time, thisMonth) {
+			continue
+		}
+		month.Tokens += r.Tokens()
+		month.CostUSD += r.CostUSD
+		if strings.HasPrefix(r.Time, today) {
+			day.Tokens += r.Tokens()
+			day.CostUSD += r.CostUSD
+		}
+	}
+	return day, month, nil
+}
+
+// CheckBudget returns an error wrapping ErrBudgetExhausted when a daily or
+// monthly limit has been reached.
+func CheckBudget(gitRoot string, cfg *config.Config) error {
+	b := cfg.Budget
+	if b == nil {
+		return nil
+	}
+	day, month, err := Spending(gitRoot, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to read run records: %w", err)
+	}
+	switch {
+	case b.DailyTokens > 0 && day.Tokens >= b.DailyTokens:
+		return fmt.Errorf("%w: %d of %d daily tokens used", ErrBudgetExhausted, day.Tokens, b.DailyTokens)
+	case b.DailyCostUSD > 0 && day.CostUSD >= b.DailyCostUSD:
+		return fmt.Errorf("%w: $%.2f of $%.2f daily cost used", ErrBudgetExhausted, day.CostUSD, b.DailyCostUSD)
+	case b.MonthlyTokens > 0 && month.Tokens >= b.MonthlyTokens:
+		return fmt.Errorf("%w: %d of %d monthly tokens used", ErrBudgetExhausted, month.Tokens, b.MonthlyTokens)
+	case b.MonthlyCostUSD > 0 && month.CostUSD >= b.MonthlyCostUSD:
+		return fmt.Errorf("%w: $%.2f of $%.2f monthly cost used", ErrBudgetExhausted, month.CostUSD, b.MonthlyCostUSD)
+	}
+	return nil
+}
+
+// --- Per-run metering ---
+
+// usageMeter accumulates a run's usage while it is in progress, so failed
+// runs still record what they consumed, and cancels the run once it passes
+// the per-run token budget. Engines report cumulative usage under a key
+// (e.g. a message ID) so repeated reports aren't double-counted.
+type usageMeter struct {
+	mu     sync.Mutex
+	limit  int
+	byKey  map[string]Usage
+	cancel context.CancelCauseFunc
+}
+
+func newUsageMeter(limit int, cancel context.CancelCauseFunc) *usageMeter {
+	return &usageMeter{limit: limit, byKey: map[string]Usage{}, cancel: cancel}
+}
+
+// report records the usage for key. It is safe to call on a nil meter.
+func (m *usageMeter) report(key string, u Usage) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.byKey[key] = u
+	if m.limit > 0 && m.totalLocked().Tokens() > m.limit {
+		m.cancel(errRunBudget)
+	}
+}
+
+func (m *usageMeter) total() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.totalLocked()
+}
+
+func (m *usageMeter) totalLocked() Usage {
+	var t Usage
+	for _, u := range m.byKey {
+		t.merge(u)
+	}
+	return t
+}
+
+// lineTap passes writes through to w and calls fn for every complete line.
+type lineTap struct {
+	w   io.Writer
+	fn  func(line []byte)
+	buf []byte
+}
+
+func (t *lineTap) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	for {
+		i := bytes.IndexByte(t.buf, '\n')
+		if i < 0 {
+			break
+		}
+		t.fn(t.buf[:i])
+		t.buf = t.buf[i+1:]
+	}
+	if t.w == nil {
+		return len(p), nil
+	}
+	return t.w.Write(p)
+}
@@ -16,6 +16,12 @@ func sentinelPath(gitRoot string) string {
 	return filepath.Join(config.Dir(gitRoot), sentinelFile)
 }
 
+// SentinelPath is the loop-prevention file that exists while a wiki commit
+// is being made.
+func SentinelPath(gitRoot string) string {
+	return sentinelPath(gitRoot)
+}
+
 // IsSentinelPresent checks if a wiki commit is in progress (loop prevention).
 func IsSentinelPresent(gitRoot string) bool {
 	_, err := os.Stat(sentinelPath(gitRoot))
@@ -44,10 +50,12 @@ func CommitChanges(gitRoot string, cfg *config.Config, description string) error
 		return fmt.Errorf("failed to stage wiki files: %w", err)
 	}
 
-	// Also stage config (updated last_run, last_commit_hash)
-	configPath := config.Path(gitRoot)
-	if _, err := os.Stat(configPath); err == nil {
-		git.StageFiles(gitRoot, []string{configPath})
+	// Also stage config (updated last_run, last_commit_hash) and the
+	// outline of two-phase generation
+	for _, p := range []string{config.Path(gitRoot), config.OutlinePath(gitRoot)} {
+		if _, err := os.Stat(p); err == nil {
+			git.StageFiles(gitRoot, []string{p})
+		}
 	}
 
 	// Commit with recognizable prefix
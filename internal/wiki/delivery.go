@@ -0,0 +1,93 @@
+package wiki
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// maxArgvPrompt is the longest prompt passed on the command line in auto
+// mode. Linux caps a single argument at 128 KiB (MAX_ARG_STRLEN), and every
+// argument shows up in ps output, so prompts carrying diffs or long file
+// lists go through stdin or a temp file instead.
+const maxArgvPrompt = 16 * 1024
+
+// filePromptf is the short instruction passed as the prompt argument when
+// the real prompt was written to a file the agent reads itself.
+const filePromptf = "Your task instructions are in the file %s. Read that whole file first and then carry out the instructions exactly. Do not modify or delete it."
+
+// promptDeliverer is implemented by engines that can receive the prompt
+// other than as a command-line argument. PromptDeliveries lists the
+// prompt_delivery values the engine accepts besides auto, in order of
+// preference for long prompts.
+type promptDeliverer interface {
+	PromptDeliveries() []string
+}
+
+// choosePromptDelivery resolves the configured prompt_delivery for a prompt.
+// Auto keeps the prompt on the command line unless it is long and the engine
+// lists an alternative to argv. Engines that don't list argv at all, like the
+// command engine, take the prompt the way their configuration says.
+func choosePromptDelivery(configured string, supported []string, prompt string) string {
+	if configured != "" && configured != config.PromptDeliveryAuto {
+		return configured
+	}
+	if len(prompt) > maxArgvPrompt && containsString(supported, config.PromptDeliveryArgv) {
+		for _, d := range supported {
+			if d != config.PromptDeliveryArgv {
+				return d
+			}
+		}
+	}
+	return config.PromptDeliveryArgv
+}
+
+// validatePromptDelivery checks the prompt_delivery option of the named
+// engine against what the engine supports.
+func validatePromptDelivery(e Engine, opts config.EngineOptions) error {
+	d := opts.PromptDelivery
+	if d == "" || d == config.PromptDeliveryAuto {
+		return nil
+	}
+	pd, ok := e.(promptDeliverer)
+	if !ok {
+		return nil
+	}
+	if supported := pd.PromptDeliveries(); !containsString(supported, d) {
+		return fmt.Errorf("engines.%s.prompt_delivery %q is not supported by the %s engine (supported: auto, %s)",
+			e.Name(), d, e.Name(), strings.Join(supported, ", "))
+	}
+	return nil
+}
+
+// writePromptFile writes prompt to a new temp file and returns its path.
+// The caller removes it.
+func writePromptFile(prompt string) (string, error) {
+	f, err := os.CreateTemp("", "repowiki-prompt-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create prompt file: %w", err)
+	}
+	_, werr := f.WriteString(prompt)
+	if cerr := f.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write prompt file: %w", werr)
+	}
+	return f.Name(), nil
+}
+
+// promptArg is the prompt argument for an agent CLI: the prompt itself, or
+// an instruction to read the prompt file. It is "" for stdin delivery.
+func promptArg(inv *Invocation) string {
+	switch inv.PromptVia {
+	case config.PromptDeliveryStdin:
+		return ""
+	case config.PromptDeliveryFile:
+		return fmt.Sprintf(filePromptf, inv.PromptFile)
+	}
+	return inv.Prompt
+}
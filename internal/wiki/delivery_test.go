@@ -0,0 +1,65 @@
+package wiki
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+func TestChoosePromptDelivery(t *testing.T) {
+	short := strings.Repeat("x", maxArgvPrompt)
+	long := short + "x"
+	tests := []struct {
+		engine     string
+		configured string
+		prompt     string
+		want       string
+	}{
+		{config.EngineClaudeCode, "", short, config.PromptDeliveryArgv},
+		{config.EngineClaudeCode, config.PromptDeliveryAuto, long, config.PromptDeliveryStdin},
+		{config.EngineClaudeCode, config.PromptDeliveryFile, short, config.PromptDeliveryFile},
+		{config.EngineCodex, "", long, config.PromptDeliveryStdin},
+		{config.EngineCodex, config.PromptDeliveryArgv, long, config.PromptDeliveryArgv},
+		{config.EngineQoder, "", short, config.PromptDeliveryArgv},
+		{config.EngineQoder, "", long, config.PromptDeliveryFile},
+		{config.EngineQoder, config.PromptDeliveryArgv, long, config.PromptDeliveryArgv},
+		// The command engine's template decides unless stdin is configured.
+		{config.EngineCommand, "", long, config.PromptDeliveryArgv},
+		{config.EngineCommand, config.PromptDeliveryStdin, short, config.PromptDeliveryStdin},
+	}
+	for _, tt := range tests {
+		e, err := LookupEngine(tt.engine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		supported := e.(promptDeliverer).PromptDeliveries()
+		if got := choosePromptDelivery(tt.configured, supported, tt.prompt); got != tt.want {
+			t.Errorf("%s, configured %q, %d-byte prompt: got %s, want %s", tt.engine, tt.configured, len(tt.prompt), got, tt.want)
+		}
+	}
+}
+
+func TestValidatePromptDelivery(t *testing.T) {
+	tests := []struct {
+		engine   string
+		delivery string
+		ok       bool
+	}{
+		{config.EngineClaudeCode, config.PromptDeliveryStdin, true},
+		{config.EngineQoder, config.PromptDeliveryFile, true},
+		{config.EngineQoder, config.PromptDeliveryStdin, false},
+		{config.EngineCommand, config.PromptDeliveryAuto, true},
+		{config.EngineCommand, config.PromptDeliveryFile, false},
+	}
+	for _, tt := range tests {
+		e, err := LookupEngine(tt.engine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = validatePromptDelivery(e, config.EngineOptions{PromptDelivery: tt.delivery})
+		if (err == nil) != tt.ok {
+			t.Errorf("%s with %s: err = %v, want ok %v", tt.engine, tt.delivery, err, tt.ok)
+		}
+	}
+}
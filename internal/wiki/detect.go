@@ -1,145 +1,126 @@
 package wiki
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
-	"strings"
+	"regexp"
+	"sort"
 
 	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+	"github.com/ikrasnodymov/repowiki/internal/pathmatch"
 )
 
-type codeSnippet struct {
-	ID       string `json:"id"`
-	Path     string `json:"path"`
-	LineRange string `json:"line_range"`
-}
-
-type metadata struct {
-	CodeSnippets []codeSnippet `json:"code_snippets"`
-}
-
-// AffectedSections determines which wiki sections need updating based on changed files.
-// It uses the metadata reverse index and heuristic path matching.
-func AffectedSections(gitRoot string, cfg *config.Config, changedFiles []string) []string {
-	affected := map[string]bool{}
-
-	// 1. Build reverse index from metadata
-	reverseIdx := buildReverseIndex(gitRoot, cfg)
-	for _, f := range changedFiles {
-		if pages, ok := reverseIdx[f]; ok {
-			for _, p := range pages {
-				affected[p] = true
-			}
+// AffectedSections determines which wiki sections need updating based on
+// the files changed up to commitHash, most likely first. Each section is
+// scored by the wiki pages citing the files, the source globs of the
+// configured sections, the section rules, and the pages mentioning changed
+// Go symbols; sections scoring above zero are returned. A citing page only
+// counts when the change reaches the lines it documents.
+func AffectedSections(gitRoot string, cfg *config.Config, changes []git.FileChange, commitHash string, symbols []SymbolChange) []string {
+	score := map[string]int{}
+	changedFiles := changedPaths(changes)
+	// Sections are matched by path on both sides of a rename.
+	matchFiles := changedFiles
+	for _, c := range changes {
+		if c.Status == git.StatusRenamed {
+			matchFiles = append(matchFiles, c.OldPath)
 		}
 	}
 
-	// 2. Heuristic path matching
+	// 1. Pages citing the changed lines
+	refs, err := BuildRefIndex(gitRoot, cfg)
+	if err != nil {
+		logf(gitRoot, "cannot index wiki citations: %v", err)
+	}
+	changed := changedLines(gitRoot, cfg, commitHash, changes)
+	recorded := snippetLines(gitRoot, cfg)
+	skipped := 0
 	for _, f := range changedFiles {
-		for _, section := range heuristicMatch(f) {
-			affected[section] = true
+		for _, p := range refs.PagesCiting(f) {
+			if !touchesDocumented(refs.CitedLines(p, f), recorded[f], changed[f]) {
+				skipped++
+				continue
+			}
+			score[p]++
 		}
 	}
-
-	result := make([]string, 0, len(affected))
-	for s := range affected {
-		result = append(result, s)
-	}
-	return result
-}
-
-// buildReverseIndex reads repowiki-metadata.json and cross-references with
-// wiki content files to map source files -> wiki pages that reference them.
-func buildReverseIndex(gitRoot string, cfg *config.Config) map[string][]string {
-	idx := map[string][]string{}
-
-	metaPath := filepath.Join(gitRoot, cfg.WikiPath, cfg.Language, "meta", "repowiki-metadata.json")
-	data, err := os.ReadFile(metaPath)
-	if err != nil {
-		return idx
+	if skipped > 0 {
+		logf(gitRoot, "ignored %d page citation(s) of changed files: changes are outside the documented lines", skipped)
 	}
 
-	var meta metadata
-	if err := json.Unmarshal(data, &meta); err != nil {
-		return idx
+	// 2. Source globs of the configured sections
+	for _, sec := range cfg.WikiSections() {
+		for _, f := range matchFiles {
+			if pathmatch.MatchAny(sec.Sources, f) {
+				score[sec.Title]++
+			}
+		}
 	}
 
-	// Collect all source file paths from metadata
-	sourceFiles := map[string]bool{}
-	for _, s := range meta.CodeSnippets {
-		sourceFiles[s.Path] = true
+	// 3. Section rules, the default ones for the default outline
+	for _, r := range compileRules(gitRoot, cfg.DetectionRules()) {
+		for _, f := range matchFiles {
+			if r.match(f) {
+				score[r.Section] += r.weight()
+			}
+		}
 	}
 
-	// Scan wiki content files for <cite> blocks referencing source files
-	contentDir := filepath.Join(gitRoot, cfg.WikiPath, cfg.Language, "content")
-	scanWikiContent(contentDir, "", sourceFiles, idx)
-
-	return idx
-}
-
-func scanWikiContent(dir string, relDir string, sourceFiles map[string]bool, idx map[string][]string) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return
+	// 4. Pages mentioning changed Go symbols
+	for _, c := range symbols {
+		for _, p := range c.Pages {
+			score[p]++
+		}
 	}
 
-	for _, e := range entries {
-		if e.IsDir() {
-			subRel := filepath.Join(relDir, e.Name())
-			scanWikiContent(filepath.Join(dir, e.Name()), subRel, sourceFiles, idx)
-			continue
+	result := make([]string, 0, len(score))
+	for s, n := range score {
+		if n > 0 {
+			result = append(result, s)
 		}
-
-		if !strings.HasSuffix(e.Name(), ".md") {
-			continue
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if score[result[i]] != score[result[j]] {
+			return score[result[i]] > score[result[j]]
 		}
+		return result[i] < result[j]
+	})
+	return result
+}
 
-		wikiPage := filepath.Join(relDir, e.Name())
-		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
-		if err != nil {
-			continue
-		}
+type sectionRule struct {
+	config.SectionRule
+	re *regexp.Regexp
+}
 
-		content := string(data)
-		// Look for file:// references in <cite> blocks
-		for srcFile := range sourceFiles {
-			if strings.Contains(content, "file://"+srcFile) || strings.Contains(content, srcFile) {
-				idx[srcFile] = append(idx[srcFile], wikiPage)
+// compileRules compiles the regexes of the rules, skipping (and logging)
+// invalid ones.
+func compileRules(gitRoot string, rules []config.SectionRule) []sectionRule {
+	compiled := make([]sectionRule, 0, len(rules))
+	for _, r := range rules {
+		sr := sectionRule{SectionRule: r}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				logf(gitRoot, "skipping section rule for %q: %v", r.Section, err)
+				continue
 			}
+			sr.re = re
 		}
+		compiled = append(compiled, sr)
 	}
+	return compiled
 }
 
-func heuristicMatch(filePath string) []string {
-	var sections []string
-	lower := strings.ToLower(filePath)
-
-	switch {
-	case strings.Contains(lower, "backend/") || strings.Contains(lower, "server/") || strings.Contains(lower, "src/api/"):
-		sections = append(sections, "Backend Architecture")
-	case strings.Contains(lower, "frontend/") || strings.Contains(lower, "src/components/") || strings.Contains(lower, "src/app/"):
-		sections = append(sections, "Frontend Architecture")
-	}
-
-	if strings.Contains(lower, "api/") || strings.Contains(lower, "routes/") || strings.Contains(lower, "endpoints/") {
-		sections = append(sections, "API Reference")
-	}
-
-	if strings.Contains(lower, "config") || strings.Contains(lower, ".env") || strings.Contains(lower, "settings") {
-		sections = append(sections, "Configuration Management")
-	}
-
-	if strings.HasSuffix(lower, "readme.md") || strings.HasSuffix(lower, "package.json") || strings.HasSuffix(lower, "pyproject.toml") {
-		sections = append(sections, "System Overview")
-	}
-
-	if strings.Contains(lower, "auth") || strings.Contains(lower, "security") {
-		sections = append(sections, "Authentication and Security")
+func (r sectionRule) match(path string) bool {
+	if r.re != nil {
+		return r.re.MatchString(path)
 	}
+	return pathmatch.Match(r.Glob, path)
+}
 
-	if strings.Contains(lower, "database/") || strings.Contains(lower, "models/") || strings.Contains(lower, "migrations/") {
-		sections = append(sections, "Backend Architecture")
+func (r sectionRule) weight() int {
+	if r.Weight == 0 {
+		return 1
 	}
-
-	return sections
+	return r.Weight
 }
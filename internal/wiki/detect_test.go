@@ -0,0 +1,87 @@
+package wiki
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+func TestDefaultSectionRules(t *testing.T) {
+	rules := compileRules(t.TempDir(), config.DefaultSectionRules())
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"server/handlers/user.go", []string{"Backend Architecture"}},
+		{"src/api/users.ts", []string{"Backend Architecture", "API Reference"}},
+		{"src/components/Button.tsx", []string{"Frontend Architecture"}},
+		{"routes/index.js", []string{"API Reference"}},
+		{"config/database.yml", []string{"Configuration Management"}},
+		{".env.example", []string{"Configuration Management"}},
+		{"README.md", []string{"System Overview"}},
+		{"docs/README.md", []string{"System Overview"}},
+		{"pkg/Auth/token.go", nil},
+		{"db/models/user.py", []string{"Backend Architecture"}},
+		{"internal/util/strings.go", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range rules {
+			if r.match(tt.path) {
+				got = append(got, r.Section)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s matches %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultSectionRulesNameDefaultSections(t *testing.T) {
+	titles := map[string]bool{}
+	for _, s := range config.DefaultSections() {
+		titles[s.Title] = true
+	}
+	for _, r := range config.DefaultSectionRules() {
+		if !titles[r.Section] {
+			t.Errorf("default rule %s names %q, which is not a default section", r.Regex+r.Glob, r.Section)
+		}
+	}
+}
+
+func TestAffectedSectionsRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.Sections = []config.Section{
+		{Title: "Engines", Sources: []string{"internal/wiki/engine*.go"}},
+		{Title: "Git", Sources: []string{"internal/git/"}},
+		{Title: "CLI"},
+	}
+	cfg.SectionRules = []config.SectionRule{
+		{Glob: "cmd/", Section: "CLI", Weight: 3},
+		{Regex: `_test\.go$`, Section: "Engines", Weight: -5},
+		{Regex: `^internal/git/`, Section: "Engines"},
+	}
+	changes := []git.FileChange{
+		{Status: git.StatusModified, Path: "cmd/repowiki/update.go"},
+		{Status: git.StatusModified, Path: "internal/git/git.go"},
+		{Status: git.StatusRenamed, OldPath: "internal/wiki/engine_old.go", Path: "internal/wiki/backend.go"},
+		{Status: git.StatusAdded, Path: "internal/wiki/engine_test.go"},
+	}
+	// CLI: 3 from its rule. Git: 1 from its sources. Engines: 2 from its
+	// sources (the test and the old name of the rename), 1 from the git
+	// rule, -5 from the test rule.
+	got := AffectedSections(t.TempDir(), cfg, changes, "HEAD", nil)
+	if want := []string{"CLI", "Git"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AffectedSections = %v, want %v", got, want)
+	}
+
+	// Without the negative rule Engines ranks by its score of 3, tied with
+	// CLI and ordered by name.
+	cfg.SectionRules = append(cfg.SectionRules[:1], cfg.SectionRules[2:]...)
+	got = AffectedSections(t.TempDir(), cfg, changes, "HEAD", nil)
+	if want := []string{"CLI", "Engines", "Git"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AffectedSections = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package wiki
+
+import (
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// maxPromptDiffBytes caps the diffs of one incremental prompt as a whole;
+// files past it are listed without a diff.
+const maxPromptDiffBytes = 64 * 1024
+
+// FileDiff is the diff of one changed file as shown in a prompt.
+type FileDiff struct {
+	Path string
+	Diff string
+	// Truncated is set when Diff was cut at diff_max_bytes.
+	Truncated bool
+}
+
+// CommitInfo is one commit of the range an incremental update covers.
+type CommitInfo struct {
+	Hash    string
+	Subject string
+	// Body is the message without the subject line, possibly empty.
+	Body string
+}
+
+// commitRange returns the commits an update for hash covers: from the last
+// processed commit, or just hash itself when there is none. It matches how
+// `repowiki update` picks the changed files.
+func commitRange(cfg *config.Config, hash string) (from string, to string) {
+	if cfg.LastCommitHash != "" && cfg.LastCommitHash != hash {
+		return cfg.LastCommitHash, hash
+	}
+	return "", hash
+}
+
+// collectDiffs returns the diff of each changed file between from and to,
+// each cut at cfg.DiffLimit bytes. Deleted files, files whose diff can't be
+// read, and files past the prompt-wide cap are left out.
+func collectDiffs(gitRoot string, cfg *config.Config, from string, to string, changes []git.FileChange) []FileDiff {
+	limit := cfg.DiffLimit()
+	if limit == 0 {
+		return nil
+	}
+	var diffs []FileDiff
+	total := 0
+	for i, c := range changes {
+		if c.Status == git.StatusDeleted {
+			continue
+		}
+		d, err := git.FileDiff(gitRoot, from, to, diffPaths(c)...)
+		if err != nil || d == "" {
+			continue
+		}
+		fd := FileDiff{Path: c.Path, Diff: d}
+		if len(d) > limit {
+			fd.Diff, fd.Truncated = truncateLines(d, limit), true
+		}
+		if total+len(fd.Diff) > maxPromptDiffBytes {
+			logf(gitRoot, "prompt diff budget of %d bytes reached, leaving out %d more file(s)", maxPromptDiffBytes, 1+countUndeleted(changes[i+1:]))
+			break
+		}
+		total += len(fd.Diff)
+		diffs = append(diffs, fd)
+	}
+	return diffs
+}
+
+// countUndeleted counts the changes that are not deletions.
+func countUndeleted(changes []git.FileChange) int {
+	n := 0
+	for _, c := range changes {
+		if c.Status != git.StatusDeleted {
+			n++
+		}
+	}
+	return n
+}
+
+// collectCommits returns the messages of the commits between from and to,
+// skipping repowiki's own wiki commits.
+func collectCommits(gitRoot string, cfg *config.Config, from string, to string) []CommitInfo {
+	hashes, err := git.CommitsBetween(gitRoot, from, to)
+	if err != nil {
+		logf(gitRoot, "cannot list commits: %v", err)
+		return nil
+	}
+	var commits []CommitInfo
+	for _, h := range hashes {
+		msg, err := git.CommitMessage(gitRoot, h)
+		if err != nil || strings.HasPrefix(msg, cfg.CommitPrefix) {
+			continue
+		}
+		subject, body, _ := strings.Cut(msg, "\n")
+		if len(h) > 12 {
+			h = h[:12]
+		}
+		commits = append(commits, CommitInfo{Hash: h, Subject: subject, Body: strings.TrimSpace(body)})
+	}
+	return commits
+}
+
+// truncateLines cuts s to at most n bytes, at a line boundary if there is
+// one.
+func truncateLines(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if s[n] == '\n' {
+		return s[:n]
+	}
+	s = s[:n]
+	if i := strings.LastIndexByte(s, '\n'); i > 0 {
+		s = s[:i]
+	}
+	return s
+}
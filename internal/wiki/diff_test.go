@@ -0,0 +1,122 @@
+package wiki
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+func TestTruncateLines(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short\n", 10, "short\n"},
+		{"one\ntwo\nthree\n", 14, "one\ntwo\nthree\n"},
+		{"one\ntwo\nthree\n", 9, "one\ntwo"},
+		{"one\ntwo\nthree\n", 7, "one\ntwo"},
+		{"one\ntwo\nthree\n", 6, "one"},
+		{"no newline at all", 5, "no ne"},
+		{"\nleading", 4, "\nlea"},
+	}
+	for _, tt := range tests {
+		if got := truncateLines(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateLines(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+// bigFile returns about n bytes of numbered lines.
+func bigFile(name string, n int) string {
+	var b strings.Builder
+	for i := 1; b.Len() < n; i++ {
+		fmt.Fprintf(&b, "%s line %d\n", name, i)
+	}
+	return b.String()
+}
+
+func TestCollectDiffs(t *testing.T) {
+	root := newTestRepo(t, map[string]string{
+		"a.go": "a\n", "b.go": "b\n", "c.go": "c\n", "d.go": "d\n", "e.go": "e\n",
+	})
+	from := strings.TrimSpace(gitCmd(t, root, "rev-parse", "HEAD"))
+	writeTestFile(t, root, "a.go", bigFile("a", 30000))
+	writeTestFile(t, root, "b.go", bigFile("b", 30000))
+	writeTestFile(t, root, "c.go", bigFile("c", 30000))
+	writeTestFile(t, root, "d.go", "d changed\n")
+	gitCmd(t, root, "rm", "-q", "e.go")
+	gitCmd(t, root, "commit", "-q", "-am", "change")
+	to := strings.TrimSpace(gitCmd(t, root, "rev-parse", "HEAD"))
+	changes := []git.FileChange{
+		{Status: git.StatusModified, Path: "a.go"},
+		{Status: git.StatusModified, Path: "b.go"},
+		{Status: git.StatusModified, Path: "c.go"},
+		{Status: git.StatusModified, Path: "d.go"},
+		{Status: git.StatusDeleted, Path: "e.go"},
+	}
+
+	t.Run("prompt-wide cap", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.DiffMaxBytes = 40000
+		diffs := collectDiffs(root, cfg, from, to, changes)
+		total := 0
+		var paths []string
+		for _, d := range diffs {
+			paths = append(paths, d.Path)
+			total += len(d.Diff)
+			if d.Truncated {
+				t.Errorf("%s: truncated under diff_max_bytes", d.Path)
+			}
+		}
+		if strings.Join(paths, ",") != "a.go,b.go" {
+			t.Errorf("diffs of %v, want a.go and b.go", paths)
+		}
+		if total > maxPromptDiffBytes {
+			t.Errorf("diffs total %d bytes, over the cap of %d", total, maxPromptDiffBytes)
+		}
+		logs, _ := filepath.Glob(filepath.Join(config.LogPath(root), "*.log"))
+		var log []byte
+		for _, l := range logs {
+			data, _ := os.ReadFile(l)
+			log = append(log, data...)
+		}
+		if !strings.Contains(string(log), "leaving out 2 more file(s)") {
+			t.Errorf("log does not report c.go and d.go left out:\n%s", log)
+		}
+	})
+
+	t.Run("per-file limit", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.DiffMaxBytes = 200
+		diffs := collectDiffs(root, cfg, from, to, changes)
+		if len(diffs) != 4 {
+			t.Fatalf("got %d diffs, want 4", len(diffs))
+		}
+		for _, d := range diffs {
+			if len(d.Diff) > 200 || d.Truncated != (d.Path != "d.go") {
+				t.Errorf("%s: %d bytes, truncated %v", d.Path, len(d.Diff), d.Truncated)
+			}
+			full, err := git.FileDiff(root, from, to, d.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Truncated && !strings.HasPrefix(full, d.Diff+"\n") {
+				t.Errorf("%s: not cut at a line boundary", d.Path)
+			}
+		}
+	})
+
+	t.Run("diffs disabled", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.DiffMaxBytes = -1
+		if diffs := collectDiffs(root, cfg, from, to, changes); diffs != nil {
+			t.Errorf("got %d diffs with diff_max_bytes -1", len(diffs))
+		}
+	})
+}
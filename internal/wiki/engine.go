@@ -2,165 +2,709 @@ package wiki
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"runtime"
-	"strconv"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ikrasnodymov/repowiki/internal/config"
 )
 
-// FindEngineBinary locates the CLI binary for the configured engine.
-func FindEngineBinary(cfg *config.Config) (string, error) {
-	switch cfg.Engine {
-	case config.EngineQoder:
-		return findQoderBinary(cfg)
-	case config.EngineClaudeCode:
-		return findClaudeCodeBinary(cfg)
-	case config.EngineCodex:
-		return findCodexBinary(cfg)
-	default:
-		return "", fmt.Errorf("unknown engine: %s (valid: qoder, claude-code, codex)", cfg.Engine)
+// Engine is an AI backend that repowiki drives to write wiki pages.
+// Each implementation lives in its own engine_*.go file and registers
+// itself from init().
+type Engine interface {
+	// Name is the identifier used in config.json and --engine.
+	Name() string
+	// Detect locates the engine executable and returns its path.
+	Detect(cfg *config.Config) (string, error)
+	// Version reports the installed engine version.
+	Version(cfg *config.Config) (string, error)
+	// BuildArgs returns the command-line arguments (without the binary)
+	// for a non-interactive run.
+	BuildArgs(inv *Invocation) []string
+	// Run executes the engine for a single prompt. Cancelling ctx must stop
+	// the engine and everything it spawned.
+	Run(ctx context.Context, inv *Invocation) (*Result, error)
+	// ParseResult extracts the result from the engine's raw stdout.
+	ParseResult(stdout string) (*Result, error)
+}
+
+// Modes of a Request.
+const (
+	ModeFull        = "full"
+	ModeIncremental = "incremental"
+	// ModeOutline asks for a page outline as JSON in the engine's answer;
+	// ModePage writes one page of it.
+	ModeOutline = "outline"
+	ModePage    = "page"
+	// ModeSection writes one section of the configured wiki outline.
+	ModeSection = "section"
+	// ModeProbe is a trivial prompt sent by ProbeEngine.
+	ModeProbe = "probe"
+)
+
+// Request is the work handed to an engine.
+type Request struct {
+	Mode   string
+	Prompt string
+	// Files lists the source files the request concerns (the changed files
+	// of an incremental update); nil means the whole repository.
+	Files []string
+	// Page is the page a ModePage or ModeSection request writes, relative
+	// to the wiki content directory; it ends in "/" for a directory
+	// section.
+	Page string
+}
+
+// Invocation describes a single engine run.
+type Invocation struct {
+	Request
+	Cfg     *config.Config
+	GitRoot string
+	// PromptFile is the path of a file holding Prompt, for engines that
+	// read the prompt from disk.
+	PromptFile string
+	// PromptVia is how Prompt is passed to an external command, one of
+	// config.PromptDeliveryArgv (the default when empty), Stdin or File.
+	PromptVia string
+	// Stdout and Stderr receive the engine's output as it is produced,
+	// in addition to the buffered copy used for ParseResult. Either may be nil.
+	Stdout io.Writer
+	Stderr io.Writer
+	// meter receives usage reports from engines that know their usage
+	// while running.
+	meter *usageMeter
+}
+
+// Result is what an engine run produced.
+type Result struct {
+	Engine string
+	Output string
+	Usage  Usage
+}
+
+// Usage is what a run consumed, as far as the engine reports it. Zero fields
+// are unknown.
+type Usage struct {
+	Model           string  `json:"model,omitempty"`
+	InputTokens     int     `json:"input_tokens,omitempty"`
+	OutputTokens    int     `json:"output_tokens,omitempty"`
+	CacheReadTokens int     `json:"cache_read_tokens,omitempty"`
+	CostUSD         float64 `json:"cost_usd,omitempty"`
+	Turns           int     `json:"turns,omitempty"`
+}
+
+// summary renders the known usage fields for log lines, with a leading
+// separator, or "" when nothing was reported.
+func (u Usage) summary() string {
+	var parts []string
+	if u.InputTokens > 0 || u.OutputTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d in / %d out tokens", u.InputTokens+u.CacheReadTokens, u.OutputTokens))
+	}
+	if u.Turns > 0 {
+		parts = append(parts, fmt.Sprintf("%d turns", u.Turns))
+	}
+	if u.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.4f", u.CostUSD))
 	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(parts, ", ")
 }
 
-// RunEngine invokes the configured engine with the given prompt in non-interactive mode.
-func RunEngine(cfg *config.Config, gitRoot string, prompt string) (string, error) {
-	switch cfg.Engine {
-	case config.EngineQoder:
-		return runQoder(cfg, gitRoot, prompt)
-	case config.EngineClaudeCode:
-		return runClaudeCode(cfg, gitRoot, prompt)
-	case config.EngineCodex:
-		return runCodex(cfg, gitRoot, prompt)
-	default:
-		return "", fmt.Errorf("unknown engine: %s", cfg.Engine)
+// merge adds o to u.
+func (u *Usage) merge(o Usage) {
+	if u.Model == "" {
+		u.Model = o.Model
 	}
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CostUSD += o.CostUSD
+	u.Turns += o.Turns
 }
 
-// --- Qoder CLI ---
+type registeredEngine struct {
+	engine      Engine
+	detectOrder int
+}
 
-func findQoderBinary(cfg *config.Config) (string, error) {
-	if cfg.EnginePath != "" {
-		if _, err := os.Stat(cfg.EnginePath); err == nil {
-			return cfg.EnginePath, nil
+var registry = map[string]registeredEngine{}
+
+// RegisterEngine adds an engine to the registry. detectOrder ranks the engine
+// during auto-detection (lower is tried first); zero excludes it from
+// auto-detection.
+func RegisterEngine(e Engine, detectOrder int) {
+	if _, dup := registry[e.Name()]; dup {
+		panic("wiki: engine registered twice: " + e.Name())
+	}
+	registry[e.Name()] = registeredEngine{engine: e, detectOrder: detectOrder}
+}
+
+// LookupEngine returns the registered engine with the given name.
+func LookupEngine(name string) (Engine, error) {
+	r, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown engine: %s (valid: %s)", name, strings.Join(EngineNames(), ", "))
+	}
+	return r.engine, nil
+}
+
+// IsValidEngine reports whether an engine with the given name is registered.
+func IsValidEngine(name string) bool {
+	_, ok := registry[name]
+	return ok
+}
+
+// EngineNames lists all registered engines, auto-detectable ones first in
+// detection order, the rest alphabetically.
+func EngineNames() []string {
+	entries := make([]registeredEngine, 0, len(registry))
+	for _, r := range registry {
+		entries = append(entries, r)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.detectOrder > 0) != (b.detectOrder > 0) {
+			return a.detectOrder > 0
+		}
+		if a.detectOrder != b.detectOrder {
+			return a.detectOrder < b.detectOrder
 		}
+		return a.engine.Name() < b.engine.Name()
+	})
+	names := make([]string, len(entries))
+	for i, r := range entries {
+		names[i] = r.engine.Name()
 	}
-	if path, err := exec.LookPath("qodercli"); err == nil {
-		return path, nil
+	return names
+}
+
+// DetectOrder lists the engines tried during auto-detection, in order.
+func DetectOrder() []string {
+	var names []string
+	for _, name := range EngineNames() {
+		if registry[name].detectOrder > 0 {
+			names = append(names, name)
+		}
 	}
-	if runtime.GOOS == "darwin" {
-		for _, p := range []string{
-			"/Applications/Qoder.app/Contents/Resources/app/resources/bin/aarch64_darwin/qodercli",
-			"/Applications/Qoder.app/Contents/Resources/app/resources/bin/x86_64_darwin/qodercli",
-		} {
-			if _, err := os.Stat(p); err == nil {
-				return p, nil
+	return names
+}
+
+// validator is implemented by engines whose configuration can be checked
+// before a run.
+type validator interface {
+	Validate(cfg *config.Config) error
+}
+
+// optionSupporter is implemented by engines that accept a per-engine options
+// block; it lists the config names of the options the engine honours.
+type optionSupporter interface {
+	SupportedOptions() []string
+}
+
+// runBudgeter is implemented by engines that can honour per-run budget
+// limits: run_tokens needs the token usage the engine reports, run_cost_usd
+// a cost limit of the engine's own.
+type runBudgeter interface {
+	RunBudget() (tokens bool, cost bool)
+}
+
+// ValidateEngine checks that the configured engine and its fallbacks exist,
+// that every per-engine options block only uses options its engine supports,
+// that every engine honours the per-run budget limits, and that the
+// engine-specific settings are usable.
+func ValidateEngine(cfg *config.Config) error {
+	names := make([]string, 0, len(cfg.Engines))
+	for name := range cfg.Engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := validateEngineOptions(cfg, name); err != nil {
+			return err
+		}
+	}
+	for _, name := range EngineChain(cfg) {
+		e, err := LookupEngine(name)
+		if err != nil {
+			return err
+		}
+		if err := validateRunBudget(cfg.Budget, name, e); err != nil {
+			return err
+		}
+		if v, ok := e.(validator); ok {
+			if err := v.Validate(EngineConfig(cfg, name)); err != nil {
+				return err
 			}
 		}
 	}
-	return "", fmt.Errorf("qodercli not found; install Qoder or set engine_path in config")
+	return nil
 }
 
-func runQoder(cfg *config.Config, gitRoot string, prompt string) (string, error) {
-	bin, err := findQoderBinary(cfg)
+// validateRunBudget rejects per-run limits the engine would silently ignore.
+func validateRunBudget(b *config.BudgetConfig, name string, e Engine) error {
+	if b == nil {
+		return nil
+	}
+	var tokens, cost bool
+	if r, ok := e.(runBudgeter); ok {
+		tokens, cost = r.RunBudget()
+	}
+	switch {
+	case b.RunTokens > 0 && !tokens:
+		return fmt.Errorf("budget.run_tokens is not enforced by the %s engine, which reports no token usage", name)
+	case b.RunCostUSD > 0 && !cost:
+		return fmt.Errorf("budget.run_cost_usd is not enforced by the %s engine; only %s can stop a run at a cost", name, config.EngineClaudeCode)
+	}
+	return nil
+}
+
+func validateEngineOptions(cfg *config.Config, name string) error {
+	e, err := LookupEngine(name)
+	if err != nil {
+		return fmt.Errorf("engines.%s: %w", name, err)
+	}
+	var supported []string
+	if s, ok := e.(optionSupporter); ok {
+		supported = s.SupportedOptions()
+	}
+	opts := cfg.EngineOptions(name)
+	for _, opt := range opts.Set() {
+		if !containsString(supported, opt) {
+			list := strings.Join(supported, ", ")
+			if list == "" {
+				list = "none"
+			}
+			return fmt.Errorf("engines.%s.%s is not supported by the %s engine (supported: %s)", name, opt, name, list)
+		}
+	}
+	return validatePromptDelivery(e, opts)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandLine renders the command line the configured engine would run,
+// with the prompt shown as a placeholder. It is empty for engines that don't
+// run an external command.
+func CommandLine(cfg *config.Config, gitRoot string) (string, error) {
+	e, err := LookupEngine(cfg.Engine)
 	if err != nil {
 		return "", err
 	}
-	args := []string{
-		"-p", prompt,
-		"-q",
-		"-w", gitRoot,
-		"--max-turns", strconv.Itoa(cfg.MaxTurns),
-		"--dangerously-skip-permissions",
-		"--allowed-tools", "Read,Write,Edit,Glob,Grep,Bash",
+	cfg = EngineConfig(cfg, cfg.Engine)
+	bin, err := e.Detect(cfg)
+	if err != nil {
+		return "", err
+	}
+	inv := &Invocation{Cfg: cfg, GitRoot: gitRoot, Request: Request{Prompt: "<prompt>"}, PromptFile: "<prompt-file>"}
+	if pd, ok := e.(promptDeliverer); ok {
+		inv.PromptVia = choosePromptDelivery(cfg.EngineOptions(cfg.Engine).PromptDelivery, pd.PromptDeliveries(), inv.Prompt)
+	}
+	args := e.BuildArgs(inv)
+	if args == nil {
+		return "", nil
+	}
+	parts := []string{shellQuote(bin)}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
 	}
-	if cfg.Model != "" {
-		args = append(args, "--model", cfg.Model)
+	if inv.PromptVia == config.PromptDeliveryStdin {
+		parts = append(parts, "< <prompt>")
 	}
-	return execCLI(bin, gitRoot, args)
+	return strings.Join(parts, " "), nil
 }
 
-// --- Claude Code ---
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`|&;<>()*?[]{}!#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
 
-func findClaudeCodeBinary(cfg *config.Config) (string, error) {
-	if cfg.EnginePath != "" {
-		if _, err := os.Stat(cfg.EnginePath); err == nil {
-			return cfg.EnginePath, nil
+// FindEngineBinary locates the CLI binary for the configured engine.
+func FindEngineBinary(cfg *config.Config) (string, error) {
+	e, err := LookupEngine(cfg.Engine)
+	if err != nil {
+		return "", err
+	}
+	return e.Detect(cfg)
+}
+
+// RunEngine invokes the configured engine with the request's prompt in non-interactive mode.
+// Transient failures are retried with exponential backoff; if the engine
+// still fails, each of cfg.FallbackEngines is tried in turn with the same
+// prompt. Result.Engine names the engine that succeeded. Failures are
+// returned as *EngineError (joined when several engines failed). A chain
+// that fails ValidateEngine is not run at all, so that settings an engine
+// does not support are reported rather than dropped.
+func RunEngine(ctx context.Context, cfg *config.Config, gitRoot string, req Request, opts Options) (*Result, error) {
+	if err := ValidateEngine(cfg); err != nil {
+		logf(gitRoot, "invalid engine config: %v", err)
+		return nil, fmt.Errorf("invalid engine config: %w", err)
+	}
+	chain := EngineChain(cfg)
+	var errs []error
+	for i, name := range chain {
+		e, _ := LookupEngine(name) // checked by ValidateEngine
+		res, err := runWithRetries(ctx, e, EngineConfig(cfg, name), gitRoot, req, opts)
+		recordEngineStatus(gitRoot, name, err)
+		if err == nil {
+			if i > 0 {
+				logf(gitRoot, "fallback engine %s succeeded", name)
+			}
+			return res, nil
+		}
+		errs = append(errs, err)
+		if kind := ErrorKindOf(err); ctx.Err() != nil || kind == ErrInterrupted || kind == ErrBudget {
+			// Interrupted by the user or over budget — don't start
+			// another engine.
+			break
+		}
+		if i+1 < len(chain) {
+			logf(gitRoot, "engine %s failed, falling back to %s: %v", name, chain[i+1], err)
 		}
 	}
-	if path, err := exec.LookPath("claude"); err == nil {
-		return path, nil
+	if len(errs) == 1 {
+		return nil, errs[0]
 	}
-	// Common locations
-	home, _ := os.UserHomeDir()
-	for _, p := range []string{
-		home + "/.local/bin/claude",
-		home + "/.claude/bin/claude",
-		"/usr/local/bin/claude",
-	} {
-		if _, err := os.Stat(p); err == nil {
-			return p, nil
+	return nil, fmt.Errorf("all engines failed: %w", errors.Join(errs...))
+}
+
+// retryAfter waits out the backoff before a retry; tests replace it.
+var retryAfter = time.After
+
+// runWithRetries runs one engine, retrying transient failures (rate limits,
+// network errors) up to cfg.MaxRetries times with exponential backoff.
+func runWithRetries(ctx context.Context, e Engine, cfg *config.Config, gitRoot string, req Request, opts Options) (*Result, error) {
+	backoff := cfg.RetryBackoff()
+	for attempt := 1; ; attempt++ {
+		res, err := runEngineOnce(ctx, e, cfg, gitRoot, req, opts)
+		kind := ErrorKindOf(err)
+		if err == nil || !kind.Transient() || attempt > cfg.MaxRetries {
+			return res, err
+		}
+		logf(gitRoot, "engine %s %s, retry %d/%d in %s", e.Name(), kind.describe(), attempt, cfg.MaxRetries, backoff)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-retryAfter(backoff):
 		}
+		backoff *= 2
+	}
+}
+
+// EngineChain is the ordered list of engines RunEngine tries: the configured
+// engine followed by its fallbacks, without duplicates.
+func EngineChain(cfg *config.Config) []string {
+	chain := []string{cfg.Engine}
+	seen := map[string]bool{cfg.Engine: true}
+	for _, name := range cfg.FallbackEngines {
+		if !seen[name] {
+			seen[name] = true
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// EngineConfig returns cfg adjusted to run the named engine: its options
+// block overrides model and max_turns, and since engine_path belongs to the
+// primary engine, fallbacks locate their own binaries.
+func EngineConfig(cfg *config.Config, name string) *config.Config {
+	opts := cfg.EngineOptions(name)
+	if name == cfg.Engine && opts.Model == "" && opts.MaxTurns == 0 {
+		return cfg
+	}
+	c := *cfg
+	if name != cfg.Engine {
+		c.Engine = name
+		c.EnginePath = ""
+	}
+	if opts.Model != "" {
+		c.Model = opts.Model
+	}
+	if opts.MaxTurns > 0 {
+		c.MaxTurns = opts.MaxTurns
 	}
-	return "", fmt.Errorf("claude not found; install Claude Code or set engine_path in config")
+	return &c
 }
 
-func runClaudeCode(cfg *config.Config, gitRoot string, prompt string) (string, error) {
-	bin, err := findClaudeCodeBinary(cfg)
+// runEngineOnce runs a single engine, bounded by the configured timeout and
+// aborted when ctx is cancelled. Engine output is streamed to a per-run log
+// under .repowiki/logs/runs/.
+func runEngineOnce(ctx context.Context, e Engine, cfg *config.Config, gitRoot string, req Request, opts Options) (*Result, error) {
+	timeout := cfg.RunTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+	runTokens := 0
+	if cfg.Budget != nil {
+		runTokens = cfg.Budget.RunTokens
+	}
+
+	inv := &Invocation{Cfg: cfg, GitRoot: gitRoot, Request: req, meter: newUsageMeter(runTokens, cancelRun)}
+	rl, err := openRunLog(gitRoot, e.Name(), opts.Echo)
 	if err != nil {
-		return "", err
+		logf(gitRoot, "run log unavailable: %v", err)
+	} else {
+		defer rl.Close()
+		logf(gitRoot, "engine %s output: %s", e.Name(), rl.path)
+		inv.Stdout, inv.Stderr = rl.stream("stdout"), rl.stream("stderr")
 	}
-	args := []string{
-		"-p", prompt,
-		"--dangerously-skip-permissions",
-		"--allowedTools", "Read,Write,Edit,Glob,Grep,Bash",
+
+	start := time.Now()
+	res, err := e.Run(ctx, inv)
+	if err != nil {
+		ee := classifyError(ctx, e, err)
+		if ee.Kind == ErrTimeout {
+			ee.Err = fmt.Errorf("after %s: %w", timeout, ee.Err)
+		}
+		if h, ok := e.(hinter); ok && ee.Hint == "" {
+			ee.Hint = h.Hint(ee.Kind)
+		}
+		err = ee
+		if rl != nil {
+			rl.finish("engine %s failed after %s: %v", e.Name(), time.Since(start).Round(time.Second), err)
+		}
+		recordRun(gitRoot, cfg, req.Mode, start, inv.meter.total(), err)
+		return nil, err
 	}
-	if cfg.Model != "" {
-		args = append(args, "--model", cfg.Model)
+	if rl != nil {
+		rl.finish("engine %s finished in %s%s", e.Name(), time.Since(start).Round(time.Second), res.Usage.summary())
 	}
-	return execCLI(bin, gitRoot, args)
+	if runTokens > 0 && res.Usage.Tokens() > runTokens {
+		// Engines without live usage reports can only be checked afterwards.
+		logf(gitRoot, "engine %s used %d tokens, over the per-run budget of %d", e.Name(), res.Usage.Tokens(), runTokens)
+	}
+	recordRun(gitRoot, cfg, req.Mode, start, res.Usage, nil)
+	res.Engine = e.Name()
+	return res, nil
 }
 
-// --- Codex CLI ---
+// --- CLI-based engines ---
 
-func findCodexBinary(cfg *config.Config) (string, error) {
+// cliEngine is an Engine backed by an agent CLI that accepts the prompt on
+// its command line. Engines only differ in how the binary is found and how
+// arguments are built.
+type cliEngine struct {
+	name        string
+	binary      string
+	installHint string
+	// authHint tells the user how to (re-)authenticate the CLI.
+	authHint string
+	// errorPatterns are engine-specific stderr fragments checked before
+	// the common ones when classifying failures.
+	errorPatterns errorPatterns
+	// extraPaths returns well-known install locations checked after $PATH.
+	extraPaths func() []string
+	buildArgs  func(inv *Invocation) []string
+	// parseResult extracts the result and usage from structured output;
+	// nil means stdout is the plain-text result.
+	parseResult func(stdout string) (*Result, error)
+	// options lists the per-engine options the CLI supports. Extra args and
+	// env are handled generically; buildArgs maps the others to flags.
+	options []string
+	// meterLine reads live usage from one line of stdout; ok is false for
+	// lines without usage. Reports are keyed so repeats replace each other.
+	meterLine func(line []byte) (key string, u Usage, ok bool)
+	// deliveries lists how the CLI can receive the prompt, in order of
+	// preference for long prompts; buildArgs uses promptArg accordingly.
+	deliveries []string
+	// limitsCost is set for CLIs that stop a run at budget.run_cost_usd
+	// themselves.
+	limitsCost bool
+}
+
+func (e *cliEngine) Name() string { return e.name }
+
+func (e *cliEngine) Detect(cfg *config.Config) (string, error) {
 	if cfg.EnginePath != "" {
 		if _, err := os.Stat(cfg.EnginePath); err == nil {
 			return cfg.EnginePath, nil
 		}
 	}
-	if path, err := exec.LookPath("codex"); err == nil {
+	if path, err := exec.LookPath(e.binary); err == nil {
 		return path, nil
 	}
-	return "", fmt.Errorf("codex not found; install OpenAI Codex CLI or set engine_path in config")
+	if e.extraPaths != nil {
+		for _, p := range e.extraPaths() {
+			if _, err := os.Stat(p); err == nil {
+				return p, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s not found; %s or set engine_path in config", e.binary, e.installHint)
 }
 
-func runCodex(cfg *config.Config, gitRoot string, prompt string) (string, error) {
-	bin, err := findCodexBinary(cfg)
+func (e *cliEngine) Version(cfg *config.Config) (string, error) {
+	bin, err := e.Detect(cfg)
 	if err != nil {
 		return "", err
 	}
-	args := []string{
-		"exec", prompt,
-		"--full-auto",
+	out, err := exec.Command(bin, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", bin, err)
 	}
-	return execCLI(bin, gitRoot, args)
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return version, nil
+}
+
+func (e *cliEngine) SupportedOptions() []string { return e.options }
+
+// RunBudget reports that usage is counted for CLIs with structured output.
+func (e *cliEngine) RunBudget() (tokens bool, cost bool) {
+	return e.parseResult != nil || e.meterLine != nil, e.limitsCost
+}
+
+func (e *cliEngine) PromptDeliveries() []string { return e.deliveries }
+
+func (e *cliEngine) BuildArgs(inv *Invocation) []string {
+	return append(e.buildArgs(inv), inv.Cfg.EngineOptions(e.name).ExtraArgs...)
+}
+
+// allowedTools returns the engine's allowed_tools option as a comma-separated
+// list, or def when it isn't set.
+func allowedTools(inv *Invocation, def string) string {
+	if tools := inv.Cfg.EngineOptions(inv.Cfg.Engine).AllowedTools; len(tools) > 0 {
+		return strings.Join(tools, ",")
+	}
+	return def
+}
+
+func (e *cliEngine) Run(ctx context.Context, inv *Invocation) (*Result, error) {
+	bin, err := e.Detect(inv.Cfg)
+	if err != nil {
+		return nil, &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+	inv.PromptVia = choosePromptDelivery(inv.Cfg.EngineOptions(e.name).PromptDelivery, e.deliveries, inv.Prompt)
+	if inv.PromptVia == config.PromptDeliveryFile {
+		if inv.PromptFile, err = writePromptFile(inv.Prompt); err != nil {
+			return nil, err
+		}
+		defer os.Remove(inv.PromptFile)
+	}
+	if inv.PromptVia != config.PromptDeliveryArgv {
+		logf(inv.GitRoot, "passing %d-byte prompt to %s via %s", len(inv.Prompt), e.name, inv.PromptVia)
+	}
+	run := inv
+	if e.meterLine != nil && inv.meter != nil {
+		tapped := *inv
+		tapped.Stdout = &lineTap{w: inv.Stdout, fn: func(line []byte) {
+			if key, u, ok := e.meterLine(line); ok {
+				inv.meter.report(key, u)
+			}
+		}}
+		run = &tapped
+	}
+	stdout, err := execCLI(ctx, run, bin, e.BuildArgs(inv))
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.ParseResult(stdout)
+	if err != nil {
+		var ee *EngineError
+		if errors.As(err, &ee) {
+			return nil, ee
+		}
+		return nil, &EngineError{Kind: ErrMalformedOutput, Err: err}
+	}
+	return res, nil
+}
+
+func (e *cliEngine) Classify(exitCode int, stderr string) ErrorKind {
+	return classifyStderr(exitCode, stderr, e.errorPatterns)
+}
+
+func (e *cliEngine) Hint(kind ErrorKind) string {
+	if kind == ErrAuth {
+		return e.authHint
+	}
+	return ""
+}
+
+func (e *cliEngine) ParseResult(stdout string) (*Result, error) {
+	if e.parseResult != nil {
+		return e.parseResult(stdout)
+	}
+	return &Result{Output: stdout}, nil
 }
 
 // --- Common executor ---
 
-func execCLI(bin string, dir string, args []string) (string, error) {
-	cmd := exec.Command(bin, args...)
-	cmd.Dir = dir
+// killGrace is how long an engine gets to exit after SIGTERM before the
+// whole process group is killed.
+const killGrace = 10 * time.Second
+
+// execCLI runs an engine binary in its own process group. Agents spawn
+// children through their Bash tool, so on cancellation the entire group is
+// terminated rather than just the direct child. Output is buffered for the
+// caller and teed to the invocation's stream writers.
+func execCLI(ctx context.Context, inv *Invocation, bin string, args []string) (string, error) {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Dir = inv.GitRoot
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killGrace
+	if inv.PromptVia == config.PromptDeliveryStdin {
+		cmd.Stdin = strings.NewReader(inv.Prompt)
+	}
+	if env := inv.Cfg.EngineOptions(inv.Cfg.Engine).Env; len(env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	cmd.Stdout = teeTo(&stdout, inv.Stdout)
+	cmd.Stderr = teeTo(&stderr, inv.Stderr)
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("%s error: %w\nstderr: %s", bin, err, stderr.String())
+	err := cmd.Run()
+	if cmd.Process != nil && ctx.Err() != nil {
+		// Reap anything that ignored SIGTERM or outlived the group leader.
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	if err != nil {
+		ee := &EngineError{Kind: ErrExit, Stderr: stderr.String(), Err: fmt.Errorf("%s: %w", bin, err)}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			ee.ExitCode = exitErr.ExitCode()
+		} else if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+			ee.Kind = ErrBinaryMissing
+		}
+		if ctx.Err() != nil {
+			ee.Err = fmt.Errorf("%s: %w", bin, ctx.Err())
+		}
+		return "", ee
 	}
 	return stdout.String(), nil
 }
+
+func teeTo(buf *bytes.Buffer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
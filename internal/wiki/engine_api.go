@@ -0,0 +1,210 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/llm"
+)
+
+// --- Direct HTTP API engine ---
+//
+// The api engine calls an OpenAI-compatible chat completions endpoint or the
+// Anthropic Messages API itself and runs a small tool loop (read, list, grep,
+// write) inside repowiki, so no agent CLI has to be installed.
+
+const apiSystemPrompt = `You are an autonomous documentation agent running inside repowiki.
+You can only act through the provided tools. All paths are relative to the repository root.
+Work until the task is complete, then reply with a short summary and no tool calls.`
+
+func init() {
+	RegisterEngine(apiEngine{}, 0)
+}
+
+type apiEngine struct{}
+
+func (apiEngine) Name() string { return config.EngineAPI }
+
+func (apiEngine) Validate(cfg *config.Config) error {
+	if cfg.API == nil {
+		return fmt.Errorf("api engine requires an \"api\" block in %s", config.ConfigFile)
+	}
+	if cfg.API.Provider != llm.ProviderOpenAI && cfg.API.Provider != llm.ProviderAnthropic {
+		return fmt.Errorf("api.provider must be %q or %q", llm.ProviderOpenAI, llm.ProviderAnthropic)
+	}
+	if cfg.Model == "" {
+		return fmt.Errorf("api engine requires \"model\" to be set")
+	}
+	for _, tool := range cfg.EngineOptions(config.EngineAPI).AllowedTools {
+		if !containsString(agentToolNames, tool) {
+			return fmt.Errorf("engines.api.allowed_tools: unknown tool %q (valid: %s)", tool, strings.Join(agentToolNames, ", "))
+		}
+	}
+	return nil
+}
+
+func (apiEngine) SupportedOptions() []string {
+	return []string{config.OptionModel, config.OptionMaxTurns, config.OptionAllowedTools}
+}
+
+// RunBudget reports that the tokens of every model call are counted; the
+// API has no cost limit.
+func (apiEngine) RunBudget() (tokens bool, cost bool) { return true, false }
+
+// Detect returns the endpoint the engine will call.
+func (e apiEngine) Detect(cfg *config.Config) (string, error) {
+	if err := e.Validate(cfg); err != nil {
+		return "", err
+	}
+	if cfg.API.BaseURL != "" {
+		return cfg.API.BaseURL, nil
+	}
+	return cfg.API.Provider + " (default endpoint)", nil
+}
+
+func (e apiEngine) Version(cfg *config.Config) (string, error) {
+	if err := e.Validate(cfg); err != nil {
+		return "", err
+	}
+	return cfg.API.Provider + " API, model " + cfg.Model, nil
+}
+
+// BuildArgs returns nil: the api engine doesn't run an external command.
+func (apiEngine) BuildArgs(inv *Invocation) []string { return nil }
+
+func (e apiEngine) Run(ctx context.Context, inv *Invocation) (*Result, error) {
+	if err := e.Validate(inv.Cfg); err != nil {
+		return nil, &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+	api := inv.Cfg.API
+	client, err := llm.New(api.Provider, api.BaseURL, os.Getenv(apiKeyEnv(api)), &http.Client{})
+	if err != nil {
+		return nil, &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+	return runAgentLoop(ctx, client, inv, api.MaxTokens)
+}
+
+func (apiEngine) ParseResult(stdout string) (*Result, error) {
+	return &Result{Output: stdout}, nil
+}
+
+func (apiEngine) Hint(kind ErrorKind) string {
+	if kind == ErrAuth {
+		return "check the API key in the variable named by api.api_key_env"
+	}
+	return ""
+}
+
+func apiKeyEnv(api *config.APIConfig) string {
+	if api.APIKeyEnv != "" {
+		return api.APIKeyEnv
+	}
+	if api.Provider == llm.ProviderAnthropic {
+		return "ANTHROPIC_API_KEY"
+	}
+	return "OPENAI_API_KEY"
+}
+
+// runAgentLoop drives the model through tool calls until it stops calling
+// tools, and returns the model's final text and the tokens used across all
+// turns. Reaching cfg.MaxTurns first is a failure: the wiki is likely half
+// written.
+func runAgentLoop(ctx context.Context, client llm.Client, inv *Invocation, maxTokens int) (*Result, error) {
+	tools := newAgentTools(inv.Cfg, inv.GitRoot)
+	req := &llm.Request{
+		Model:     inv.Cfg.Model,
+		System:    apiSystemPrompt,
+		Tools:     tools.definitions(),
+		MaxTokens: maxTokens,
+		Turns:     []llm.Turn{{Role: "user", Text: inv.Prompt}},
+	}
+
+	maxTurns := inv.Cfg.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = config.Default().MaxTurns
+	}
+	res := &Result{Usage: Usage{Model: inv.Cfg.Model}}
+	for turn := 1; turn <= maxTurns; turn++ {
+		resp, err := client.Chat(ctx, req)
+		if err != nil {
+			return nil, llmError(err)
+		}
+		res.Usage.add(resp.Usage)
+		inv.meter.report("", res.Usage)
+		if resp.Text != "" {
+			writeStream(inv.Stdout, resp.Text)
+		}
+		if len(resp.Calls) == 0 {
+			res.Output = resp.Text
+			return res, nil
+		}
+
+		req.Turns = append(req.Turns, llm.Turn{Role: "assistant", Text: resp.Text, Calls: resp.Calls})
+		results := make([]llm.ToolResult, 0, len(resp.Calls))
+		for _, c := range resp.Calls {
+			writeStream(inv.Stdout, fmt.Sprintf("[turn %d] %s %s", turn, c.Name, truncate(string(c.Args), 200)))
+			r := tools.call(c)
+			if r.IsError {
+				writeStream(inv.Stderr, r.Content)
+			}
+			results = append(results, r)
+		}
+		req.Turns = append(req.Turns, llm.Turn{Role: "user", Results: results})
+	}
+	err := fmt.Errorf("stopped after max turns (%d) before the model finished", maxTurns)
+	writeStream(inv.Stderr, err.Error())
+	return nil, &EngineError{Kind: ErrExit, Err: err, Hint: "raise max_turns"}
+}
+
+// add counts one model call.
+func (u *Usage) add(lu llm.Usage) {
+	u.InputTokens += lu.InputTokens
+	u.OutputTokens += lu.OutputTokens
+	u.Turns++
+}
+
+// llmError maps HTTP client failures onto engine error kinds.
+func llmError(err error) error {
+	var se *llm.StatusError
+	var de *llm.DecodeError
+	switch {
+	case errors.As(err, &se):
+		ee := &EngineError{Kind: ErrExit, Stderr: se.Body, Err: fmt.Errorf("HTTP %d", se.StatusCode)}
+		switch {
+		case se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden:
+			ee.Kind = ErrAuth
+		case se.StatusCode == http.StatusTooManyRequests || se.StatusCode == 529:
+			ee.Kind = ErrRateLimit
+		case se.StatusCode == http.StatusRequestTimeout || se.StatusCode >= 500:
+			ee.Kind = ErrNetwork
+		}
+		return ee
+	case errors.As(err, &de):
+		return &EngineError{Kind: ErrMalformedOutput, Err: err}
+	default:
+		return &EngineError{Kind: ErrNetwork, Err: err}
+	}
+}
+
+func writeStream(w io.Writer, s string) {
+	if w == nil {
+		return
+	}
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	w.Write([]byte(s))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,158 @@
+package wiki
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// --- Claude Code ---
+
+func init() {
+	// claude-code is tried first during auto-detection because it's the
+	// most commonly available.
+	RegisterEngine(&cliEngine{
+		name:        config.EngineClaudeCode,
+		binary:      "claude",
+		installHint: "install Claude Code",
+		authHint:    "run 'claude' and complete the login prompts",
+		errorPatterns: errorPatterns{
+			ErrAuth:      {"/login", "oauth token", "credit balance is too low"},
+			ErrRateLimit: {"usage limit reached"},
+		},
+		extraPaths: claudeCodePaths,
+		buildArgs:  claudeCodeArgs,
+		options: []string{
+			config.OptionModel, config.OptionMaxTurns, config.OptionAllowedTools,
+			config.OptionExtraArgs, config.OptionEnv, config.OptionPromptDelivery,
+		},
+		deliveries:  []string{config.PromptDeliveryArgv, config.PromptDeliveryStdin, config.PromptDeliveryFile},
+		parseResult: parseClaudeCodeResult,
+		meterLine:   meterClaudeCodeLine,
+		limitsCost:  true,
+	}, 1)
+}
+
+func claudeCodePaths() []string {
+	home, _ := os.UserHomeDir()
+	return []string{
+		home + "/.local/bin/claude",
+		home + "/.claude/bin/claude",
+		"/usr/local/bin/claude",
+	}
+}
+
+func claudeCodeArgs(inv *Invocation) []string {
+	// -p without a prompt argument reads the prompt from stdin.
+	args := []string{"-p"}
+	if p := promptArg(inv); p != "" {
+		args = append(args, p)
+	}
+	args = append(args,
+		"--max-turns", strconv.Itoa(inv.Cfg.MaxTurns),
+		"--dangerously-skip-permissions",
+		"--allowedTools", allowedTools(inv, "Read,Write,Edit,Glob,Grep,Bash"),
+		// stream-json keeps output flowing into the run log and ends with
+		// a result event carrying usage and cost.
+		"--output-format", "stream-json", "--verbose",
+	)
+	if inv.Cfg.Model != "" {
+		args = append(args, "--model", inv.Cfg.Model)
+	}
+	if b := inv.Cfg.Budget; b != nil && b.RunCostUSD > 0 {
+		args = append(args, "--max-budget-usd", strconv.FormatFloat(b.RunCostUSD, 'f', -1, 64))
+	}
+	return args
+}
+
+// claudeCodeEvent is the subset of a stream-json event repowiki reads.
+type claudeCodeEvent struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	Model   string `json:"model"`
+	// Message is set on "assistant" events.
+	Message struct {
+		ID    string          `json:"id"`
+		Model string          `json:"model"`
+		Usage claudeCodeUsage `json:"usage"`
+	} `json:"message"`
+	// Fields of the final "result" event.
+	IsError      bool            `json:"is_error"`
+	Result       string          `json:"result"`
+	NumTurns     int             `json:"num_turns"`
+	TotalCostUSD float64         `json:"total_cost_usd"`
+	CostUSD      float64         `json:"cost_usd"` // older CLI versions
+	Usage        claudeCodeUsage `json:"usage"`
+}
+
+type claudeCodeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+}
+
+func (u claudeCodeUsage) usage(model string) Usage {
+	return Usage{
+		Model:           model,
+		InputTokens:     u.InputTokens + u.CacheCreationInputTokens,
+		OutputTokens:    u.OutputTokens,
+		CacheReadTokens: u.CacheReadInputTokens,
+	}
+}
+
+// meterClaudeCodeLine reads the usage of one API call from an assistant
+// event. A message split over several events repeats its usage, hence the
+// message ID as key.
+func meterClaudeCodeLine(line []byte) (string, Usage, bool) {
+	var ev claudeCodeEvent
+	if json.Unmarshal(line, &ev) != nil || ev.Type != "assistant" || ev.Message.ID == "" {
+		return "", Usage{}, false
+	}
+	u := ev.Message.Usage.usage(ev.Message.Model)
+	u.Turns = 1
+	return ev.Message.ID, u, true
+}
+
+// parseClaudeCodeResult reads the model from the init event and the answer
+// and usage from the result event.
+func parseClaudeCodeResult(stdout string) (*Result, error) {
+	var model string
+	var result *claudeCodeEvent
+	sc := bufio.NewScanner(strings.NewReader(stdout))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var ev claudeCodeEvent
+		if json.Unmarshal(sc.Bytes(), &ev) != nil {
+			continue
+		}
+		switch {
+		case ev.Type == "system" && ev.Subtype == "init":
+			model = ev.Model
+		case ev.Type == "result":
+			result = &ev
+		}
+	}
+	if result == nil {
+		return nil, errors.New("no result event in claude output")
+	}
+	if result.Subtype == "error_max_budget_usd" {
+		return nil, &EngineError{Kind: ErrBudget, Err: errors.New("per-run cost budget exceeded")}
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("claude reported an error (%s): %s", result.Subtype, result.Result)
+	}
+	usage := result.Usage.usage(model)
+	usage.CostUSD = result.TotalCostUSD
+	if usage.CostUSD == 0 {
+		usage.CostUSD = result.CostUSD
+	}
+	usage.Turns = result.NumTurns
+	return &Result{Output: result.Result, Usage: usage}, nil
+}
@@ -0,0 +1,98 @@
+package wiki
+
+import (
+	"strings"
+	"testing"
+)
+
+// Lines captured from `claude -p --output-format stream-json --verbose`,
+// shortened.
+const (
+	claudeInitLine       = `{"type":"system","subtype":"init","cwd":"/repo","session_id":"5f1c","tools":["Read","Write"],"model":"claude-sonnet-4-5-20250929","permissionMode":"acceptEdits"}`
+	claudeAssistantLine  = `{"type":"assistant","message":{"id":"msg_01Xk","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","content":[{"type":"text","text":"Reading the sources."}],"stop_reason":null,"usage":{"input_tokens":4,"cache_creation_input_tokens":5120,"cache_read_input_tokens":12288,"output_tokens":38,"service_tier":"standard"}},"parent_tool_use_id":null,"session_id":"5f1c"}`
+	claudeToolResultLine = `{"type":"user","message":{"role":"user","content":[{"tool_use_id":"toolu_01","type":"tool_result","content":"package main"}]},"session_id":"5f1c"}`
+	claudeResultLine     = `{"type":"result","subtype":"success","is_error":false,"duration_ms":41250,"num_turns":7,"result":"Wrote 4 wiki pages.","session_id":"5f1c","total_cost_usd":0.0421,"usage":{"input_tokens":9,"cache_creation_input_tokens":6000,"cache_read_input_tokens":30000,"output_tokens":812}}`
+)
+
+func TestParseClaudeCodeResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout []string
+		want   *Result
+		kind   ErrorKind // of the error; "" for a plain one
+	}{
+		{
+			name:   "success",
+			stdout: []string{claudeInitLine, claudeAssistantLine, claudeToolResultLine, claudeResultLine},
+			want: &Result{Output: "Wrote 4 wiki pages.", Usage: Usage{
+				Model: "claude-sonnet-4-5-20250929", InputTokens: 6009, OutputTokens: 812,
+				CacheReadTokens: 30000, CostUSD: 0.0421, Turns: 7,
+			}},
+		},
+		{
+			name: "malformed lines skipped",
+			stdout: []string{"Warning: update available", claudeInitLine, `{"type":"assistant",`, "",
+				`{"type":"result","subtype":"success","num_turns":1,"result":"ok","cost_usd":0.5,"usage":{"input_tokens":10,"output_tokens":2}}`},
+			want: &Result{Output: "ok", Usage: Usage{
+				Model: "claude-sonnet-4-5-20250929", InputTokens: 10, OutputTokens: 2, CostUSD: 0.5, Turns: 1,
+			}},
+		},
+		{
+			name:   "over cost budget",
+			stdout: []string{claudeInitLine, `{"type":"result","subtype":"error_max_budget_usd","is_error":true,"num_turns":12,"total_cost_usd":2.01}`},
+			kind:   ErrBudget,
+		},
+		{
+			name:   "max turns",
+			stdout: []string{claudeInitLine, `{"type":"result","subtype":"error_max_turns","is_error":true,"num_turns":50}`},
+		},
+		{
+			name:   "no result event",
+			stdout: []string{claudeInitLine, claudeAssistantLine},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClaudeCodeResult(strings.Join(tt.stdout, "\n") + "\n")
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("parseClaudeCodeResult() = %+v, want an error", got)
+				}
+				if ErrorKindOf(err) != tt.kind {
+					t.Errorf("error kind = %q, want %q (%v)", ErrorKindOf(err), tt.kind, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *tt.want {
+				t.Errorf("parseClaudeCodeResult() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeterClaudeCodeLine(t *testing.T) {
+	tests := []struct {
+		line string
+		key  string
+		want Usage
+		ok   bool
+	}{
+		{claudeAssistantLine, "msg_01Xk", Usage{
+			Model: "claude-sonnet-4-5-20250929", InputTokens: 5124, OutputTokens: 38, CacheReadTokens: 12288, Turns: 1,
+		}, true},
+		{claudeInitLine, "", Usage{}, false},
+		{claudeToolResultLine, "", Usage{}, false},
+		{claudeResultLine, "", Usage{}, false},
+		{`{"type":"assistant","message":{"content":[]}}`, "", Usage{}, false},
+		{`{"type":"assistant","message":`, "", Usage{}, false},
+	}
+	for _, tt := range tests {
+		key, got, ok := meterClaudeCodeLine([]byte(tt.line))
+		if key != tt.key || got != tt.want || ok != tt.ok {
+			t.Errorf("meterClaudeCodeLine(%.40s...) = %q, %+v, %v; want %q, %+v, %v", tt.line, key, got, ok, tt.key, tt.want, tt.ok)
+		}
+	}
+}
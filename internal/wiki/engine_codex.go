@@ -0,0 +1,97 @@
+package wiki
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// --- Codex CLI ---
+
+func init() {
+	RegisterEngine(&cliEngine{
+		name:        config.EngineCodex,
+		binary:      "codex",
+		installHint: "install OpenAI Codex CLI",
+		authHint:    "run 'codex login' or set CODEX_API_KEY",
+		errorPatterns: errorPatterns{
+			ErrAuth:      {"codex login", "openai_api_key", "codex_api_key"},
+			ErrRateLimit: {"insufficient_quota"},
+		},
+		buildArgs: codexArgs,
+		// codex exec has no turn limit or tool allow-list.
+		options:     []string{config.OptionModel, config.OptionExtraArgs, config.OptionEnv, config.OptionPromptDelivery},
+		deliveries:  []string{config.PromptDeliveryArgv, config.PromptDeliveryStdin, config.PromptDeliveryFile},
+		parseResult: parseCodexResult,
+	}, 3)
+}
+
+func codexArgs(inv *Invocation) []string {
+	prompt := promptArg(inv)
+	if prompt == "" {
+		// "-" makes codex exec read the prompt from stdin.
+		prompt = "-"
+	}
+	args := []string{
+		"exec", prompt,
+		"--full-auto",
+		"--json",
+	}
+	if inv.Cfg.Model != "" {
+		args = append(args, "--model", inv.Cfg.Model)
+	}
+	return args
+}
+
+// codexEvent is the subset of a `codex exec --json` event repowiki reads.
+type codexEvent struct {
+	Type string `json:"type"`
+	Item struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"item"`
+	Usage struct {
+		InputTokens       int `json:"input_tokens"`
+		CachedInputTokens int `json:"cached_input_tokens"`
+		OutputTokens      int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// parseCodexResult sums token usage over turn.completed events and takes the
+// last agent message as the answer. Codex reports no cost or model, and
+// counts a whole prompt as one turn, so Turns counts the agent's steps
+// (commands, file changes, tool calls and messages) instead.
+func parseCodexResult(stdout string) (*Result, error) {
+	res := &Result{}
+	seen := false
+	sc := bufio.NewScanner(strings.NewReader(stdout))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var ev codexEvent
+		if json.Unmarshal(sc.Bytes(), &ev) != nil {
+			continue
+		}
+		switch ev.Type {
+		case "turn.completed":
+			seen = true
+			res.Usage.InputTokens += ev.Usage.InputTokens - ev.Usage.CachedInputTokens
+			res.Usage.CacheReadTokens += ev.Usage.CachedInputTokens
+			res.Usage.OutputTokens += ev.Usage.OutputTokens
+		case "item.completed":
+			if ev.Item.Type == "reasoning" {
+				continue
+			}
+			res.Usage.Turns++
+			if ev.Item.Type == "agent_message" {
+				res.Output = ev.Item.Text
+			}
+		}
+	}
+	if !seen {
+		return nil, errors.New("no turn.completed event in codex output")
+	}
+	return res, nil
+}
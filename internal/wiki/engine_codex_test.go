@@ -0,0 +1,63 @@
+package wiki
+
+import (
+	"strings"
+	"testing"
+)
+
+// Lines captured from `codex exec --json`, shortened.
+const (
+	codexThreadLine    = `{"type":"thread.started","thread_id":"0199a213-81c0-7800-8aa1-bbab2a035a53"}`
+	codexTurnLine      = `{"type":"turn.started"}`
+	codexReasoningLine = `{"type":"item.completed","item":{"id":"item_0","type":"reasoning","text":"**Scanning the repository**"}}`
+	codexCommandLine   = `{"type":"item.completed","item":{"id":"item_1","type":"command_execution","command":"bash -lc ls","aggregated_output":"go.mod\nmain.go\n","exit_code":0,"status":"completed"}}`
+	codexFileLine      = `{"type":"item.completed","item":{"id":"item_2","type":"file_change","changes":[{"path":".qoder/repowiki/en/content/Overview.md","kind":"add"}],"status":"completed"}}`
+	codexMessageLine   = `{"type":"item.completed","item":{"id":"item_3","type":"agent_message","text":"Wrote the overview."}}`
+	codexUsageLine     = `{"type":"turn.completed","usage":{"input_tokens":24763,"cached_input_tokens":24448,"output_tokens":122}}`
+)
+
+func TestParseCodexResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout []string
+		want   *Result
+	}{
+		{
+			name:   "one turn",
+			stdout: []string{codexThreadLine, codexTurnLine, codexReasoningLine, codexCommandLine, codexFileLine, codexMessageLine, codexUsageLine},
+			want:   &Result{Output: "Wrote the overview.", Usage: Usage{InputTokens: 315, CacheReadTokens: 24448, OutputTokens: 122, Turns: 3}},
+		},
+		{
+			name: "usage summed over turns, last message kept",
+			stdout: []string{codexTurnLine, `{"type":"item.completed","item":{"id":"item_0","type":"agent_message","text":"first"}}`, codexUsageLine,
+				codexTurnLine, codexMessageLine, `{"type":"turn.completed","usage":{"input_tokens":1000,"cached_input_tokens":0,"output_tokens":50}}`},
+			want: &Result{Output: "Wrote the overview.", Usage: Usage{InputTokens: 1315, CacheReadTokens: 24448, OutputTokens: 172, Turns: 2}},
+		},
+		{
+			name:   "malformed lines skipped",
+			stdout: []string{"Reading prompt from stdin...", codexThreadLine, `{"type":"item.completed","item":`, codexMessageLine, codexUsageLine},
+			want:   &Result{Output: "Wrote the overview.", Usage: Usage{InputTokens: 315, CacheReadTokens: 24448, OutputTokens: 122, Turns: 1}},
+		},
+		{
+			name:   "no turn.completed",
+			stdout: []string{codexThreadLine, codexTurnLine, `{"type":"turn.failed","error":{"message":"stream disconnected"}}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCodexResult(strings.Join(tt.stdout, "\n") + "\n")
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("parseCodexResult() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *tt.want {
+				t.Errorf("parseCodexResult() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
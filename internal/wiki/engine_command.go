@@ -0,0 +1,170 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// --- Generic command engine ---
+//
+// The command engine drives any agent CLI whose argv is described by the
+// "command" template in config.json, e.g.
+//
+//	"command": ["my-agent", "run", "--cwd", "{work_dir}", "--prompt-file", "{prompt_file}"]
+
+const (
+	placeholderPrompt     = "{prompt}"
+	placeholderPromptFile = "{prompt_file}"
+	placeholderWorkDir    = "{work_dir}"
+	placeholderModel      = "{model}"
+	placeholderMaxTurns   = "{max_turns}"
+)
+
+var commandPlaceholders = []string{
+	placeholderPrompt,
+	placeholderPromptFile,
+	placeholderWorkDir,
+	placeholderModel,
+	placeholderMaxTurns,
+}
+
+var placeholderRe = regexp.MustCompile(`\{[a-z_]+\}`)
+
+func init() {
+	RegisterEngine(commandEngine{}, 0)
+}
+
+type commandEngine struct{}
+
+func (commandEngine) Name() string { return config.EngineCommand }
+
+func (commandEngine) Validate(cfg *config.Config) error {
+	tmpl := cfg.Command
+	if len(tmpl) == 0 || strings.TrimSpace(tmpl[0]) == "" {
+		return fmt.Errorf("command engine requires a \"command\" template in %s", config.ConfigFile)
+	}
+	if placeholderRe.MatchString(tmpl[0]) {
+		return fmt.Errorf("command template: binary %q must not contain placeholders", tmpl[0])
+	}
+	hasPrompt := false
+	for _, arg := range tmpl[1:] {
+		for _, ph := range placeholderRe.FindAllString(arg, -1) {
+			if !isCommandPlaceholder(ph) {
+				return fmt.Errorf("command template: unknown placeholder %s (valid: %s)", ph, strings.Join(commandPlaceholders, ", "))
+			}
+			if ph == placeholderPrompt || ph == placeholderPromptFile {
+				hasPrompt = true
+			}
+		}
+	}
+	if !hasPrompt && cfg.EngineOptions(config.EngineCommand).PromptDelivery != config.PromptDeliveryStdin {
+		return fmt.Errorf("command template must pass the prompt via %s or %s, or set engines.command.prompt_delivery to %q",
+			placeholderPrompt, placeholderPromptFile, config.PromptDeliveryStdin)
+	}
+	return nil
+}
+
+func (commandEngine) SupportedOptions() []string {
+	return []string{config.OptionModel, config.OptionMaxTurns, config.OptionExtraArgs, config.OptionEnv, config.OptionPromptDelivery}
+}
+
+// PromptDeliveries only offers stdin: argv and file delivery follow from
+// the template's placeholders.
+func (commandEngine) PromptDeliveries() []string {
+	return []string{config.PromptDeliveryStdin}
+}
+
+func (commandEngine) Detect(cfg *config.Config) (string, error) {
+	if cfg.EnginePath != "" {
+		if _, err := os.Stat(cfg.EnginePath); err == nil {
+			return cfg.EnginePath, nil
+		}
+	}
+	if len(cfg.Command) == 0 {
+		return "", fmt.Errorf("command engine requires a \"command\" template in %s", config.ConfigFile)
+	}
+	path, err := exec.LookPath(cfg.Command[0])
+	if err != nil {
+		return "", fmt.Errorf("%s not found; check \"command\" or set engine_path in config", cfg.Command[0])
+	}
+	return path, nil
+}
+
+func (e commandEngine) Version(cfg *config.Config) (string, error) {
+	// Arbitrary CLIs don't share a version flag convention.
+	if _, err := e.Detect(cfg); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func (commandEngine) BuildArgs(inv *Invocation) []string {
+	if len(inv.Cfg.Command) == 0 {
+		return nil
+	}
+	r := strings.NewReplacer(
+		placeholderPrompt, inv.Prompt,
+		placeholderPromptFile, inv.PromptFile,
+		placeholderWorkDir, inv.GitRoot,
+		placeholderModel, inv.Cfg.Model,
+		placeholderMaxTurns, strconv.Itoa(inv.Cfg.MaxTurns),
+	)
+	args := make([]string, 0, len(inv.Cfg.Command)-1)
+	for _, arg := range inv.Cfg.Command[1:] {
+		args = append(args, r.Replace(arg))
+	}
+	return append(args, inv.Cfg.EngineOptions(config.EngineCommand).ExtraArgs...)
+}
+
+func (e commandEngine) Run(ctx context.Context, inv *Invocation) (*Result, error) {
+	if err := e.Validate(inv.Cfg); err != nil {
+		return nil, err
+	}
+	bin, err := e.Detect(inv.Cfg)
+	if err != nil {
+		return nil, &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+
+	if usesPlaceholder(inv.Cfg.Command, placeholderPromptFile) {
+		if inv.PromptFile, err = writePromptFile(inv.Prompt); err != nil {
+			return nil, err
+		}
+		defer os.Remove(inv.PromptFile)
+	}
+	inv.PromptVia = choosePromptDelivery(inv.Cfg.EngineOptions(config.EngineCommand).PromptDelivery, e.PromptDeliveries(), inv.Prompt)
+
+	stdout, err := execCLI(ctx, inv, bin, e.BuildArgs(inv))
+	if err != nil {
+		return nil, err
+	}
+	return e.ParseResult(stdout)
+}
+
+func (commandEngine) ParseResult(stdout string) (*Result, error) {
+	return &Result{Output: stdout}, nil
+}
+
+func isCommandPlaceholder(ph string) bool {
+	for _, p := range commandPlaceholders {
+		if p == ph {
+			return true
+		}
+	}
+	return false
+}
+
+func usesPlaceholder(tmpl []string, ph string) bool {
+	for _, arg := range tmpl {
+		if strings.Contains(arg, ph) {
+			return true
+		}
+	}
+	return false
+}
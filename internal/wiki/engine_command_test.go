@@ -0,0 +1,83 @@
+package wiki
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+func TestCommandEngineValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  []string
+		delivery string
+		ok       bool
+	}{
+		{"prompt in argv", []string{"agent", "--prompt", "{prompt}"}, "", true},
+		{"prompt file", []string{"agent", "--prompt-file={prompt_file}", "--cwd", "{work_dir}"}, "", true},
+		{"all placeholders", []string{"agent", "{prompt}", "{model}", "{max_turns}", "{work_dir}"}, "", true},
+		{"stdin without prompt", []string{"agent", "run"}, config.PromptDeliveryStdin, true},
+		{"missing prompt", []string{"agent", "run", "--model", "{model}"}, "", false},
+		{"missing prompt with argv delivery", []string{"agent", "run"}, config.PromptDeliveryArgv, false},
+		{"unknown placeholder", []string{"agent", "{prompt}", "--dir", "{workdir}"}, "", false},
+		{"placeholder in binary", []string{"{model}", "{prompt}"}, "", false},
+		{"empty template", nil, "", false},
+		{"blank binary", []string{" ", "{prompt}"}, "", false},
+	}
+	for _, tt := range tests {
+		cfg := config.Default()
+		cfg.Engine = config.EngineCommand
+		cfg.Command = tt.command
+		if tt.delivery != "" {
+			cfg.Engines = map[string]*config.EngineOptions{config.EngineCommand: {PromptDelivery: tt.delivery}}
+		}
+		if err := (commandEngine{}).Validate(cfg); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate(%q) = %v, want ok %v", tt.name, tt.command, err, tt.ok)
+		}
+	}
+}
+
+func TestCommandEngineBuildArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		extra   []string
+		want    []string
+	}{
+		{
+			name:    "substitution",
+			command: []string{"agent", "--cwd", "{work_dir}", "--model={model}", "--turns", "{max_turns}", "{prompt}"},
+			want:    []string{"--cwd", "/repo", "--model=sonnet", "--turns", "50", "write the {model} wiki"},
+		},
+		{
+			name:    "prompt file and extra args",
+			command: []string{"agent", "@{prompt_file}"},
+			extra:   []string{"--quiet"},
+			want:    []string{"@/tmp/prompt.md", "--quiet"},
+		},
+		{
+			name:    "no placeholders",
+			command: []string{"agent"},
+			want:    []string{},
+		},
+		{
+			name: "empty template",
+		},
+	}
+	for _, tt := range tests {
+		cfg := config.Default()
+		cfg.Engine = config.EngineCommand
+		cfg.Command = tt.command
+		cfg.Model = "sonnet"
+		cfg.MaxTurns = 50
+		if tt.extra != nil {
+			cfg.Engines = map[string]*config.EngineOptions{config.EngineCommand: {ExtraArgs: tt.extra}}
+		}
+		// The prompt's own braces are left alone.
+		inv := &Invocation{Cfg: cfg, GitRoot: "/repo", PromptFile: "/tmp/prompt.md", Request: Request{Prompt: "write the {model} wiki"}}
+		if got := (commandEngine{}).BuildArgs(inv); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: BuildArgs() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package wiki
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// --- Fake engine ---
+//
+// The fake engine needs no network or agent CLI. It writes one page per
+// top-level directory listing the files in it, plus an overview, and
+// records every listed file in the metadata. In two-phase generation it
+// proposes the same pages as an outline; page and section runs write the
+// single page they are given. Output depends only on the
+// prompt and the repository contents, so the hook → update → commit
+// pipeline can be exercised end to end in throwaway repositories.
+
+// fakeFailEnv makes the fake engine fail, to exercise retries and
+// fallbacks. It holds comma-separated rules kind[@page][*times]: the run
+// fails with ErrorKind kind, if it writes page when one is given, and only
+// the first times matching runs of the process when a count is given.
+const fakeFailEnv = "REPOWIKI_FAKE_FAIL"
+
+var (
+	fakeFailMu     sync.Mutex
+	fakeFailCounts = map[string]int{} // rule -> failures so far
+)
+
+const fakeRootGroup = "Root"
+
+func init() {
+	RegisterEngine(fakeEngine{}, 0)
+}
+
+type fakeEngine struct{}
+
+func (fakeEngine) Name() string { return config.EngineFake }
+
+func (fakeEngine) SupportedOptions() []string {
+	return []string{config.OptionModel}
+}
+
+func (fakeEngine) Detect(cfg *config.Config) (string, error) { return "built-in", nil }
+
+func (fakeEngine) Version(cfg *config.Config) (string, error) {
+	return "deterministic offline engine", nil
+}
+
+// BuildArgs returns nil: the fake engine doesn't run an external command.
+func (fakeEngine) BuildArgs(inv *Invocation) []string { return nil }
+
+func (e fakeEngine) Run(ctx context.Context, inv *Invocation) (*Result, error) {
+	if err := fakeFailure(inv); err != nil {
+		return nil, err
+	}
+	switch inv.Mode {
+	case ModeProbe:
+		return e.ParseResult("OK")
+	case ModeOutline:
+		return e.outline(inv)
+	case ModePage, ModeSection:
+		return e.page(ctx, inv)
+	}
+
+	groups, err := fakeGroups(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	// A full run rewrites every page; an incremental one only the pages of
+	// the changed files' groups, dropping pages whose files are all gone.
+	touched := map[string]bool{}
+	if inv.Files == nil {
+		for g := range groups {
+			touched[g] = true
+		}
+	} else {
+		for _, f := range inv.Files {
+			touched[fakeGroup(f)] = true
+		}
+	}
+
+	contentDir := filepath.Join(inv.GitRoot, inv.Cfg.WikiPath, inv.Cfg.Language, "content")
+	meta, err := loadMetadata(inv.GitRoot, inv.Cfg)
+	if err != nil {
+		meta = &metadata{}
+	}
+	for _, g := range sortedKeys(touched) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page := filepath.Join(contentDir, g+".md")
+		if len(groups[g]) == 0 {
+			if err := removeIfExists(page); err != nil {
+				return nil, &EngineError{Kind: ErrExit, Err: err}
+			}
+			writeStream(inv.Stdout, "removed "+g+".md")
+			continue
+		}
+		content, ranges := fakePage(inv.GitRoot, g, groups[g])
+		if err := writeFakeFile(page, content); err != nil {
+			return nil, &EngineError{Kind: ErrExit, Err: err}
+		}
+		for _, f := range groups[g] {
+			meta.setSnippetsAt(f, ranges[f], "")
+		}
+		writeStream(inv.Stdout, "wrote "+g+".md")
+	}
+	for _, f := range inv.Files {
+		if _, err := os.Stat(filepath.Join(inv.GitRoot, f)); os.IsNotExist(err) {
+			meta.setSnippetsAt(f, nil, "")
+		}
+	}
+	if err := saveMetadata(inv.GitRoot, inv.Cfg, meta); err != nil {
+		return nil, &EngineError{Kind: ErrExit, Err: err}
+	}
+	if err := writeFakeFile(filepath.Join(contentDir, "Overview.md"), fakeOverview(inv.Prompt, sortedKeys(groups))); err != nil {
+		return nil, &EngineError{Kind: ErrExit, Err: err}
+	}
+
+	res, _ := e.ParseResult(fmt.Sprintf("updated %d of %d pages", len(touched), len(groups)))
+	res.Usage = Usage{Model: inv.Cfg.Model, Turns: 1}
+	return res, nil
+}
+
+// outline proposes one page per top-level directory, like a full run.
+func (e fakeEngine) outline(inv *Invocation) (*Result, error) {
+	groups, err := fakeGroups(inv)
+	if err != nil {
+		return nil, err
+	}
+	o := Outline{}
+	for _, g := range sortedKeys(groups) {
+		sources := []string{g + "/"}
+		if g == fakeRootGroup {
+			sources = groups[g]
+		}
+		o.Pages = append(o.Pages, OutlinePage{Path: g + ".md", Title: g, Sources: sources})
+	}
+	data, _ := json.MarshalIndent(o, "", "  ")
+	res, _ := e.ParseResult(string(data))
+	res.Usage = Usage{Model: inv.Cfg.Model, Turns: 1}
+	return res, nil
+}
+
+// page writes inv.Page listing the request's files; a directory section
+// gets a single page named after it.
+func (e fakeEngine) page(ctx context.Context, inv *Invocation) (*Result, error) {
+	files := inv.Files
+	if files == nil {
+		groups, err := fakeGroups(inv)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range sortedKeys(groups) {
+			files = append(files, groups[g]...)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rel := inv.Page
+	if strings.HasSuffix(rel, "/") {
+		rel += path.Base(rel) + ".md"
+	}
+	title := strings.TrimSuffix(path.Base(rel), ".md")
+	content, ranges := fakePage(inv.GitRoot, title, files)
+	page := filepath.Join(inv.GitRoot, inv.Cfg.WikiPath, inv.Cfg.Language, "content", filepath.FromSlash(rel))
+	if err := writeFakeFile(page, content); err != nil {
+		return nil, &EngineError{Kind: ErrExit, Err: err}
+	}
+
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	meta, err := loadMetadata(inv.GitRoot, inv.Cfg)
+	if err != nil {
+		meta = &metadata{}
+	}
+	for _, f := range files {
+		meta.setSnippetsAt(f, ranges[f], "")
+	}
+	if err := saveMetadata(inv.GitRoot, inv.Cfg, meta); err != nil {
+		return nil, &EngineError{Kind: ErrExit, Err: err}
+	}
+	writeStream(inv.Stdout, "wrote "+rel)
+	res, _ := e.ParseResult("wrote " + rel)
+	res.Usage = Usage{Model: inv.Cfg.Model, Turns: 1}
+	return res, nil
+}
+
+// fakeFailure returns the failure fakeFailEnv requests for the run, if any.
+func fakeFailure(inv *Invocation) error {
+	spec := os.Getenv(fakeFailEnv)
+	if spec == "" {
+		return nil
+	}
+	fakeFailMu.Lock()
+	defer fakeFailMu.Unlock()
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		kind, times := rule, -1
+		if k, n, ok := strings.Cut(rule, "*"); ok {
+			if v, err := strconv.Atoi(n); err == nil {
+				kind, times = k, v
+			}
+		}
+		kind, page, hasPage := strings.Cut(kind, "@")
+		if kind == "" || hasPage && page != inv.Page {
+			continue
+		}
+		if times >= 0 {
+			if fakeFailCounts[rule] >= times {
+				continue
+			}
+			fakeFailCounts[rule]++
+		}
+		return &EngineError{Kind: ErrorKind(kind), Err: fmt.Errorf("failure requested by %s", fakeFailEnv)}
+	}
+	return nil
+}
+
+// fakeGroups maps each page name to the tracked, non-excluded files on it.
+func fakeGroups(inv *Invocation) (map[string][]string, error) {
+	files, err := git.ListFiles(inv.GitRoot)
+	if err != nil {
+		return nil, &EngineError{Kind: ErrExit, Err: err}
+	}
+	groups := map[string][]string{}
+	for _, f := range files {
+		if !isExcluded(f, inv.Cfg.ExcludedPaths) {
+			g := fakeGroup(f)
+			groups[g] = append(groups[g], f)
+		}
+	}
+	return groups, nil
+}
+
+func (fakeEngine) ParseResult(stdout string) (*Result, error) {
+	return &Result{Output: stdout}, nil
+}
+
+// fakeGroup is the page a file is documented on: its top-level directory.
+func fakeGroup(path string) string {
+	dir, _, ok := strings.Cut(path, "/")
+	if !ok {
+		return fakeRootGroup
+	}
+	return dir
+}
+
+// fakePage renders the page of one group and returns the line range
+// recorded for each file.
+func fakePage(gitRoot string, group string, files []string) (string, map[string][]string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n<cite>\n**Referenced Files in This Document**\n", group)
+	for _, f := range files {
+		fmt.Fprintf(&b, "- [%s](file://%s)\n", filepath.Base(f), f)
+	}
+	b.WriteString("</cite>\n\n## Files\n\n| File | Lines | SHA-256 |\n|------|-------|---------|\n")
+
+	ranges := map[string][]string{}
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(gitRoot, f))
+		if err != nil {
+			continue
+		}
+		lines := strings.Count(string(data), "\n")
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			lines++
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&b, "| `%s` | %d | %s |\n", f, lines, hex.EncodeToString(sum[:6]))
+		if lines > 0 {
+			ranges[f] = []string{fmt.Sprintf("1-%d", lines)}
+		}
+	}
+	return b.String(), ranges
+}
+
+func fakeOverview(prompt string, groups []string) string {
+	var b strings.Builder
+	sum := sha256.Sum256([]byte(prompt))
+	fmt.Fprintf(&b, "# Overview\n\nGenerated by the fake engine. Prompt digest: %s\n\n## Pages\n\n", hex.EncodeToString(sum[:6]))
+	for _, g := range groups {
+		fmt.Fprintf(&b, "- [%s](%s.md)\n", g, g)
+	}
+	return b.String()
+}
+
+func writeFakeFile(path string, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,500 @@
+package wiki
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+	"github.com/ikrasnodymov/repowiki/internal/llm"
+)
+
+// --- Local model engine ---
+//
+// The local engine targets an on-prem Ollama or llama.cpp server. Small
+// local models handle agentic tool use poorly, so instead of letting the
+// model explore the repository, repowiki feeds it the source in chunks that
+// fit its context window, collects per-chunk notes, and finally asks for the
+// wiki pages as plain text which repowiki writes itself.
+
+const (
+	defaultLocalURL         = "http://localhost:11434"
+	defaultLocalContextSize = 8192
+	localAPIOllama          = "ollama"
+	localAPIOpenAI          = "openai"
+
+	// charsPerToken is a conservative estimate used to size chunks.
+	charsPerToken = 3
+	// maxLocalFileBytes skips generated or vendored blobs.
+	maxLocalFileBytes = 256 * 1024
+)
+
+const localNotesPrompt = `You are reading part %d of %d of a software repository in order to document it.
+For each file below, write concise notes for a documentation writer: purpose, main types and
+functions (with their file path and line numbers), how it relates to other parts, configuration and
+side effects. Do not invent anything that is not in the code.
+
+%s`
+
+const localCondensePrompt = `Merge the following documentation notes into one shorter set of notes.
+Keep file paths, line numbers, names of types and functions, and relationships. Drop repetition.
+
+%s`
+
+const localWritePrompt = `%s
+
+You cannot read files or run tools. Everything you know about the code is in the NOTES below%s.
+Reply ONLY with the wiki pages to create or replace, each in exactly this format:
+
+=== FILE: <page path relative to the wiki content directory, e.g. Core Features/Hooks.md> ===
+<full markdown content of the page>
+=== END FILE ===
+
+The metadata file is maintained by repowiki; do not output it.
+
+NOTES:
+%s%s`
+
+// localAnswerPrompt is used for requests answered in text, like outlines,
+// rather than with pages.
+const localAnswerPrompt = `%s
+
+You cannot read files or run tools. Everything you know about the code is in the NOTES below.
+
+NOTES:
+%s`
+
+var localFileBlockRe = regexp.MustCompile(`(?s)=== FILE: (.+?) ===\n(.*?)\n?=== END FILE ===`)
+
+func init() {
+	RegisterEngine(localEngine{}, 0)
+}
+
+type localEngine struct{}
+
+func (localEngine) Name() string { return config.EngineLocal }
+
+func (localEngine) Validate(cfg *config.Config) error {
+	lc := localConfig(cfg)
+	if lc.API != localAPIOllama && lc.API != localAPIOpenAI {
+		return fmt.Errorf("local.api must be %q or %q", localAPIOllama, localAPIOpenAI)
+	}
+	if cfg.Model == "" && lc.API == localAPIOllama {
+		return fmt.Errorf("local engine requires \"model\" to be set (e.g. the Ollama model tag)")
+	}
+	if lc.ContextSize < 2048 {
+		return fmt.Errorf("local.context_size must be at least 2048 tokens")
+	}
+	return nil
+}
+
+func (localEngine) SupportedOptions() []string {
+	return []string{config.OptionModel}
+}
+
+// RunBudget reports that the tokens the server counts are metered; local
+// runs have no cost.
+func (localEngine) RunBudget() (tokens bool, cost bool) { return true, false }
+
+// Detect returns the server URL the engine will call.
+func (e localEngine) Detect(cfg *config.Config) (string, error) {
+	if err := e.Validate(cfg); err != nil {
+		return "", err
+	}
+	return localConfig(cfg).URL, nil
+}
+
+func (e localEngine) Version(cfg *config.Config) (string, error) {
+	if err := e.Validate(cfg); err != nil {
+		return "", err
+	}
+	lc := localConfig(cfg)
+	return fmt.Sprintf("%s server, model %s, %d-token context", lc.API, cfg.Model, lc.ContextSize), nil
+}
+
+// BuildArgs returns nil: the local engine doesn't run an external command.
+func (localEngine) BuildArgs(inv *Invocation) []string { return nil }
+
+func (e localEngine) Run(ctx context.Context, inv *Invocation) (*Result, error) {
+	if err := e.Validate(inv.Cfg); err != nil {
+		return nil, &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+	client, err := localClient(inv.Cfg)
+	if err != nil {
+		return nil, &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+	r := &localRun{ctx: ctx, client: client, inv: inv, contextSize: localConfig(inv.Cfg).ContextSize, usage: Usage{Model: inv.Cfg.Model}}
+	out, err := r.run()
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.ParseResult(out)
+	if err != nil {
+		return nil, err
+	}
+	res.Usage = r.usage
+	return res, nil
+}
+
+func (localEngine) ParseResult(stdout string) (*Result, error) {
+	return &Result{Output: stdout}, nil
+}
+
+// Probe sends a single short chat instead of reading the whole repository.
+func (e localEngine) Probe(ctx context.Context, inv *Invocation) error {
+	if err := e.Validate(inv.Cfg); err != nil {
+		return &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+	client, err := localClient(inv.Cfg)
+	if err != nil {
+		return &EngineError{Kind: ErrBinaryMissing, Err: err}
+	}
+	_, err = client.Chat(ctx, &llm.Request{
+		Model: inv.Cfg.Model,
+		Turns: []llm.Turn{{Role: "user", Text: inv.Prompt}},
+	})
+	if err != nil {
+		return llmError(err)
+	}
+	return nil
+}
+
+// localClient returns an llm client for the configured server API.
+func localClient(cfg *config.Config) (llm.Client, error) {
+	lc := localConfig(cfg)
+	provider, baseURL := llm.ProviderOllama, lc.URL
+	if lc.API == localAPIOpenAI {
+		provider, baseURL = llm.ProviderOpenAI, strings.TrimRight(lc.URL, "/")+"/v1"
+	}
+	return llm.New(provider, baseURL, "", &http.Client{})
+}
+
+func localConfig(cfg *config.Config) config.LocalConfig {
+	var lc config.LocalConfig
+	if cfg.Local != nil {
+		lc = *cfg.Local
+	}
+	if lc.URL == "" {
+		lc.URL = defaultLocalURL
+	}
+	if lc.API == "" {
+		lc.API = localAPIOllama
+	}
+	if lc.ContextSize == 0 {
+		lc.ContextSize = defaultLocalContextSize
+	}
+	return lc
+}
+
+// sourceChunk is a group of file excerpts small enough for one request.
+type sourceChunk struct {
+	parts []sourcePart
+	size  int
+}
+
+type sourcePart struct {
+	path       string
+	start, end int // 1-based inclusive line range
+	text       string
+}
+
+type localRun struct {
+	ctx         context.Context
+	client      llm.Client
+	inv         *Invocation
+	contextSize int
+	usage       Usage
+}
+
+// budget is the number of characters of material that fits in one request,
+// leaving room for instructions and the model's answer.
+func (r *localRun) budget() int {
+	return r.contextSize * charsPerToken / 2
+}
+
+func (r *localRun) run() (string, error) {
+	files, err := r.sourceFiles()
+	if err != nil {
+		return "", &EngineError{Kind: ErrExit, Err: err}
+	}
+	chunks := chunkSources(r.inv.GitRoot, files, r.budget())
+	if len(chunks) == 0 {
+		return "", &EngineError{Kind: ErrExit, Err: fmt.Errorf("no readable source files to document")}
+	}
+
+	var notes []string
+	for i, c := range chunks {
+		writeStream(r.inv.Stdout, fmt.Sprintf("reading chunk %d/%d (%d files)", i+1, len(chunks), len(c.parts)))
+		note, err := r.chat(fmt.Sprintf(localNotesPrompt, i+1, len(chunks), c.render()))
+		if err != nil {
+			return "", err
+		}
+		notes = append(notes, note)
+	}
+
+	prompt := r.requestPrompt()
+	merged, err := r.condense(notes, r.budget()-len(prompt))
+	if err != nil {
+		return "", err
+	}
+
+	if r.inv.Mode == ModeOutline {
+		writeStream(r.inv.Stdout, "writing outline")
+		return r.chat(fmt.Sprintf(localAnswerPrompt, prompt, merged))
+	}
+
+	pagesNote, pages := "", r.existingPages(r.budget()-len(prompt)-len(merged))
+	if pages != "" {
+		pagesNote = " and in the EXISTING PAGES section; output complete replacements for pages you change"
+		pages = "\n\nEXISTING PAGES:\n" + pages
+	}
+	writeStream(r.inv.Stdout, "writing wiki pages")
+	answer, err := r.chat(fmt.Sprintf(localWritePrompt, prompt, pagesNote, merged, pages))
+	if err != nil {
+		return "", err
+	}
+
+	written, err := r.writePages(answer)
+	if err != nil {
+		return "", &EngineError{Kind: ErrExit, Err: err}
+	}
+	if len(written) == 0 {
+		return "", &EngineError{Kind: ErrMalformedOutput, Err: fmt.Errorf("model returned no wiki pages")}
+	}
+	for _, p := range written {
+		writeStream(r.inv.Stdout, "wrote "+p)
+	}
+	if err := r.recordSnippets(chunks); err != nil {
+		writeStream(r.inv.Stderr, "failed to update metadata: "+err.Error())
+	}
+	return fmt.Sprintf("wrote %d wiki pages from %d chunks", len(written), len(chunks)), nil
+}
+
+func (r *localRun) chat(prompt string) (string, error) {
+	resp, err := r.client.Chat(r.ctx, &llm.Request{
+		Model:       r.inv.Cfg.Model,
+		Turns:       []llm.Turn{{Role: "user", Text: prompt}},
+		ContextSize: r.contextSize,
+	})
+	if err != nil {
+		return "", llmError(err)
+	}
+	r.usage.add(resp.Usage)
+	r.inv.meter.report("", r.usage)
+	return resp.Text, nil
+}
+
+// requestPrompt is the request's prompt, cut at a line boundary to half
+// the budget so that the notes still fit beside it; incremental prompts
+// carry up to maxPromptDiffBytes of diffs.
+func (r *localRun) requestPrompt() string {
+	limit := r.budget() / 2
+	if len(r.inv.Prompt) <= limit {
+		return r.inv.Prompt
+	}
+	writeStream(r.inv.Stderr, fmt.Sprintf("prompt of %d characters exceeds the context, keeping the first %d", len(r.inv.Prompt), limit))
+	return truncateLines(r.inv.Prompt, limit)
+}
+
+// condense merges notes pairwise-by-budget until they fit in limit
+// characters.
+func (r *localRun) condense(notes []string, limit int) (string, error) {
+	for {
+		joined := strings.Join(notes, "\n\n")
+		if len(joined) <= limit || len(notes) == 1 {
+			return truncate(joined, limit), nil
+		}
+		var next []string
+		var group []string
+		size := 0
+		flush := func() error {
+			if len(group) == 0 {
+				return nil
+			}
+			writeStream(r.inv.Stdout, fmt.Sprintf("condensing %d notes", len(group)))
+			out, err := r.chat(fmt.Sprintf(localCondensePrompt, strings.Join(group, "\n\n")))
+			if err != nil {
+				return err
+			}
+			next = append(next, out)
+			group, size = nil, 0
+			return nil
+		}
+		for _, n := range notes {
+			if size+len(n) > r.budget() {
+				if err := flush(); err != nil {
+					return "", err
+				}
+			}
+			group = append(group, n)
+			size += len(n)
+		}
+		if err := flush(); err != nil {
+			return "", err
+		}
+		if len(next) >= len(notes) {
+			// Notes didn't shrink; stop rather than loop forever.
+			return truncate(strings.Join(next, "\n\n"), limit), nil
+		}
+		notes = next
+	}
+}
+
+// sourceFiles lists the files to feed: the request's files, or every
+// tracked file outside excluded paths.
+func (r *localRun) sourceFiles() ([]string, error) {
+	files := r.inv.Files
+	if files == nil {
+		var err error
+		files, err = git.ListFiles(r.inv.GitRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var result []string
+	for _, f := range files {
+		if !isExcluded(f, r.inv.Cfg.ExcludedPaths) {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// existingPages renders wiki pages mentioning the request's files, for
+// incremental updates, within the given character budget.
+func (r *localRun) existingPages(budget int) string {
+	if r.inv.Files == nil || budget <= 0 {
+		return ""
+	}
+	contentDir := filepath.Join(r.inv.GitRoot, r.inv.Cfg.WikiPath, r.inv.Cfg.Language, "content")
+	var b strings.Builder
+	filepath.Walk(contentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		for _, f := range r.inv.Files {
+			if bytes.Contains(data, []byte(f)) {
+				rel, _ := filepath.Rel(contentDir, path)
+				block := fmt.Sprintf("=== FILE: %s ===\n%s\n=== END FILE ===\n", filepath.ToSlash(rel), data)
+				if b.Len()+len(block) <= budget {
+					b.WriteString(block)
+				}
+				break
+			}
+		}
+		return nil
+	})
+	return b.String()
+}
+
+// writePages parses FILE blocks from the model's answer and writes them
+// into the wiki content directory.
+func (r *localRun) writePages(answer string) ([]string, error) {
+	contentDir := filepath.Join(r.inv.GitRoot, r.inv.Cfg.WikiPath, r.inv.Cfg.Language, "content")
+	var written []string
+	for _, m := range localFileBlockRe.FindAllStringSubmatch(answer, -1) {
+		rel := filepath.Clean(strings.TrimSpace(m[1]))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.Ext(rel) != ".md" {
+			writeStream(r.inv.Stderr, "skipping invalid page path "+m[1])
+			continue
+		}
+		full := filepath.Join(contentDir, rel)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(full, []byte(strings.TrimSpace(m[2])+"\n"), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, filepath.ToSlash(rel))
+	}
+	return written, nil
+}
+
+// recordSnippets adds the fed line ranges to repowiki-metadata.json.
+func (r *localRun) recordSnippets(chunks []sourceChunk) error {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	meta, err := loadMetadata(r.inv.GitRoot, r.inv.Cfg)
+	if err != nil {
+		meta = &metadata{}
+	}
+	ranges := map[string][]string{}
+	var order []string
+	for _, c := range chunks {
+		for _, p := range c.parts {
+			if _, ok := ranges[p.path]; !ok {
+				order = append(order, p.path)
+			}
+			ranges[p.path] = append(ranges[p.path], fmt.Sprintf("%d-%d", p.start, p.end))
+		}
+	}
+	for _, path := range order {
+		meta.setSnippets(path, ranges[path])
+	}
+	return saveMetadata(r.inv.GitRoot, r.inv.Cfg, meta)
+}
+
+func (c *sourceChunk) render() string {
+	var b strings.Builder
+	for _, p := range c.parts {
+		fmt.Fprintf(&b, "--- %s (lines %d-%d) ---\n%s\n", p.path, p.start, p.end, p.text)
+	}
+	return b.String()
+}
+
+// chunkSources packs files into chunks of at most budget characters,
+// splitting large files on line boundaries. Binary and oversized files are
+// skipped.
+func chunkSources(gitRoot string, files []string, budget int) []sourceChunk {
+	var chunks []sourceChunk
+	var cur sourceChunk
+	flush := func() {
+		if len(cur.parts) > 0 {
+			chunks = append(chunks, cur)
+			cur = sourceChunk{}
+		}
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(gitRoot, f))
+		if err != nil || len(data) == 0 || len(data) > maxLocalFileBytes || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+		lines := strings.SplitAfter(string(data), "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		part := func(from, to int) sourcePart {
+			return sourcePart{path: f, start: from + 1, end: to, text: strings.Join(lines[from:to], "")}
+		}
+
+		start, size := 0, 0
+		for i, line := range lines {
+			if cur.size+size+len(line) > budget {
+				if size > 0 {
+					cur.parts = append(cur.parts, part(start, i))
+					cur.size += size
+					start, size = i, 0
+				}
+				flush()
+			}
+			size += len(line)
+		}
+		if size > 0 {
+			cur.parts = append(cur.parts, part(start, len(lines)))
+			cur.size += size
+		}
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,115 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+func TestLocalRunFitsPromptInBudget(t *testing.T) {
+	stub := &stubChat{replies: []string{
+		textReply("notes on main.go"),
+		textReply("=== FILE: Overview.md ===\n# Overview\n=== END FILE ==="),
+	}}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	root := t.TempDir()
+	writeTestFile(t, root, "main.go", "package main\n\nfunc main() {}\n")
+	cfg := config.Default()
+	cfg.Engine = config.EngineLocal
+	cfg.Model = "stub"
+	cfg.Local = &config.LocalConfig{URL: srv.URL, API: localAPIOpenAI, ContextSize: 2048}
+	writeTestFile(t, root, filepath.Join(cfg.WikiPath, cfg.Language, "content", "Main.md"),
+		"# Main\n\nDocuments main.go.\n"+strings.Repeat("Existing text.\n", 200))
+
+	// An incremental prompt carrying diffs far larger than the context.
+	prompt := "Update the wiki for these diffs:\n" + strings.Repeat("+\tfmt.Println(\"changed\")\n", maxPromptDiffBytes/24)
+	inv := &Invocation{Cfg: cfg, GitRoot: root, Request: Request{Mode: ModeIncremental, Prompt: prompt, Files: []string{"main.go"}}}
+	if _, err := (localEngine{}).Run(context.Background(), inv); err != nil {
+		t.Fatal(err)
+	}
+
+	budget := (&localRun{contextSize: 2048}).budget()
+	if len(stub.requests) != 2 {
+		t.Fatalf("got %d requests, want notes and write", len(stub.requests))
+	}
+	text := stub.requests[1]["messages"].([]any)[0].(map[string]any)["content"].(string)
+	if limit := budget + len(localWritePrompt); len(text) > limit {
+		t.Errorf("write request has %d characters, want at most %d", len(text), limit)
+	}
+	if !strings.Contains(text, "Update the wiki for these diffs:") {
+		t.Error("write request lost the start of the prompt")
+	}
+	if strings.Contains(text, "Existing text.") {
+		t.Error("write request includes an existing page that does not fit")
+	}
+}
+
+func TestChunkSources(t *testing.T) {
+	root := t.TempDir()
+	lines := func(name string, n int) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(&b, "%-4s%05d\n", name, i) // 10 bytes
+		}
+		return b.String()
+	}
+	writeTestFile(t, root, "a.go", lines("a.go", 4))
+	writeTestFile(t, root, "b.go", lines("b.go", 4))
+	writeTestFile(t, root, "c.go", lines("c.go", 4))
+	writeTestFile(t, root, "big.go", lines("big", 25))
+	writeTestFile(t, root, "empty.go", "")
+	writeTestFile(t, root, "huge.go", lines("huge", maxLocalFileBytes/10+1))
+	writeTestFile(t, root, "logo.png", "\x89PNG\r\n\x1a\n\x00\x00")
+
+	tests := []struct {
+		name  string
+		files []string
+		want  []string // chunks, as path:start-end of their parts
+	}{
+		{"packed", []string{"a.go", "b.go"}, []string{"a.go:1-4 b.go:1-4"}},
+		{"split at the boundary", []string{"a.go", "b.go", "c.go"}, []string{"a.go:1-4 b.go:1-4 c.go:1-2", "c.go:3-4"}},
+		{"larger than a chunk", []string{"a.go", "big.go"}, []string{"a.go:1-4 big.go:1-6", "big.go:7-16", "big.go:17-25"}},
+		{"skipped files", []string{"empty.go", "logo.png", "huge.go", "missing.go", "a.go"}, []string{"a.go:1-4"}},
+		{"nothing readable", []string{"empty.go", "logo.png"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkSources(root, tt.files, 100)
+			var got []string
+			for _, c := range chunks {
+				var parts []string
+				size := 0
+				for _, p := range c.parts {
+					parts = append(parts, fmt.Sprintf("%s:%d-%d", p.path, p.start, p.end))
+					size += len(p.text)
+				}
+				if size != c.size || size > 100 {
+					t.Errorf("chunk %v: size %d, recorded %d, budget 100", parts, size, c.size)
+				}
+				got = append(got, strings.Join(parts, " "))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The parts of a split file add up to the file.
+	var text strings.Builder
+	for _, c := range chunkSources(root, []string{"big.go"}, 100) {
+		for _, p := range c.parts {
+			text.WriteString(p.text)
+		}
+	}
+	if text.String() != lines("big", 25) {
+		t.Error("parts of big.go do not add up to the file")
+	}
+}
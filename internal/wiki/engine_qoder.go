@@ -0,0 +1,55 @@
+package wiki
+
+import (
+	"runtime"
+	"strconv"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// --- Qoder CLI ---
+
+func init() {
+	RegisterEngine(&cliEngine{
+		name:        config.EngineQoder,
+		binary:      "qodercli",
+		installHint: "install Qoder",
+		authHint:    "run 'qodercli /login' or set QODER_PERSONAL_ACCESS_TOKEN",
+		errorPatterns: errorPatterns{
+			ErrAuth: {"qoder_personal_access_token", "please login"},
+		},
+		extraPaths: qoderPaths,
+		buildArgs:  qoderArgs,
+		options: []string{
+			config.OptionModel, config.OptionMaxTurns, config.OptionAllowedTools,
+			config.OptionExtraArgs, config.OptionEnv, config.OptionPromptDelivery,
+		},
+		// qodercli has no stdin mode, so long prompts go through a file.
+		deliveries: []string{config.PromptDeliveryArgv, config.PromptDeliveryFile},
+	}, 2)
+}
+
+func qoderPaths() []string {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	return []string{
+		"/Applications/Qoder.app/Contents/Resources/app/resources/bin/aarch64_darwin/qodercli",
+		"/Applications/Qoder.app/Contents/Resources/app/resources/bin/x86_64_darwin/qodercli",
+	}
+}
+
+func qoderArgs(inv *Invocation) []string {
+	args := []string{
+		"-p", promptArg(inv),
+		"-q",
+		"-w", inv.GitRoot,
+		"--max-turns", strconv.Itoa(inv.Cfg.MaxTurns),
+		"--dangerously-skip-permissions",
+		"--allowed-tools", allowedTools(inv, "Read,Write,Edit,Glob,Grep,Bash"),
+	}
+	if inv.Cfg.Model != "" {
+		args = append(args, "--model", inv.Cfg.Model)
+	}
+	return args
+}
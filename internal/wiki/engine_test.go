@@ -0,0 +1,126 @@
+package wiki
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+func TestValidateRunBudget(t *testing.T) {
+	tests := []struct {
+		engine string
+		budget config.BudgetConfig
+		ok     bool
+	}{
+		{config.EngineClaudeCode, config.BudgetConfig{RunTokens: 1000, RunCostUSD: 2}, true},
+		{config.EngineCodex, config.BudgetConfig{RunTokens: 1000}, true},
+		{config.EngineCodex, config.BudgetConfig{RunCostUSD: 2}, false},
+		{config.EngineAPI, config.BudgetConfig{RunTokens: 1000}, true},
+		{config.EngineLocal, config.BudgetConfig{RunCostUSD: 2}, false},
+		{config.EngineQoder, config.BudgetConfig{RunTokens: 1000}, false},
+		{config.EngineQoder, config.BudgetConfig{DailyCostUSD: 5, MonthlyTokens: 1000}, true},
+	}
+	for _, tt := range tests {
+		e, err := LookupEngine(tt.engine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = validateRunBudget(&tt.budget, tt.engine, e)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s with %+v: err = %v, want ok %v", tt.engine, tt.budget, err, tt.ok)
+		}
+	}
+}
+
+func TestRunEngineRejectsUnsupportedOptions(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"main.go": "package main\n"})
+	cfg := config.Default()
+	cfg.Engine = config.EngineFake
+	cfg.Engines = map[string]*config.EngineOptions{
+		config.EngineCodex: {AllowedTools: []string{"Read"}},
+	}
+	_, err := RunEngine(context.Background(), cfg, root, Request{Mode: ModeFull, Prompt: "document"}, Options{})
+	if err == nil {
+		t.Fatal("RunEngine: no error for engines.codex.allowed_tools")
+	}
+	if _, err := os.Stat(filepath.Join(root, cfg.WikiPath)); !os.IsNotExist(err) {
+		t.Errorf("engine ran despite the invalid config: %v", err)
+	}
+}
+
+// fakeFallback is a second fake engine, so that chains can fall back.
+type fakeFallback struct{ fakeEngine }
+
+func (fakeFallback) Name() string { return "fake-fallback" }
+
+func init() {
+	RegisterEngine(fakeFallback{}, 0)
+}
+
+func TestRunEngineRetriesAndFallbacks(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     string
+		retries  int
+		fallback bool
+		engine   string    // engine that succeeds; "" when the run fails
+		kind     ErrorKind // of the failure
+		joined   bool      // failure lists several engines
+		waits    []time.Duration
+	}{
+		{name: "success", engine: "fake"},
+		{name: "transient retried", fail: "rate_limit*2", retries: 2, engine: "fake", waits: []time.Duration{time.Second, 2 * time.Second}},
+		{name: "retries exhausted", fail: "network", retries: 1, kind: ErrNetwork, waits: []time.Duration{time.Second}},
+		{name: "fallback after retries", fail: "network*3", retries: 1, fallback: true, engine: "fake-fallback", waits: []time.Duration{time.Second, time.Second}},
+		{name: "auth falls back unretried", fail: "auth*1", retries: 2, fallback: true, engine: "fake-fallback"},
+		{name: "budget stops chain", fail: "budget*1", retries: 2, fallback: true, kind: ErrBudget},
+		{name: "interrupt stops chain", fail: "interrupted*1", retries: 2, fallback: true, kind: ErrInterrupted},
+		{name: "all engines fail", fail: "exit", fallback: true, kind: ErrExit, joined: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failFake(t, tt.fail)
+			var waits []time.Duration
+			retryAfter = func(d time.Duration) <-chan time.Time {
+				waits = append(waits, d)
+				c := make(chan time.Time, 1)
+				c <- time.Time{}
+				return c
+			}
+			t.Cleanup(func() { retryAfter = time.After })
+
+			cfg := config.Default()
+			cfg.Engine = config.EngineFake
+			cfg.MaxRetries = tt.retries
+			cfg.RetryBackoffSeconds = 1
+			if tt.fallback {
+				cfg.FallbackEngines = []string{"fake-fallback"}
+			}
+			res, err := RunEngine(context.Background(), cfg, t.TempDir(), Request{Mode: ModeProbe, Prompt: "ping"}, Options{})
+			if tt.engine != "" {
+				if err != nil {
+					t.Fatalf("RunEngine() = %v, want success", err)
+				}
+				if res.Engine != tt.engine {
+					t.Errorf("engine = %s, want %s", res.Engine, tt.engine)
+				}
+			} else {
+				if ErrorKindOf(err) != tt.kind {
+					t.Fatalf("RunEngine() = %v, want a %s error", err, tt.kind)
+				}
+				if joined := strings.Contains(err.Error(), "all engines failed"); joined != tt.joined {
+					t.Errorf("RunEngine() = %v, want joined %v", err, tt.joined)
+				}
+			}
+			if !slices.Equal(waits, tt.waits) {
+				t.Errorf("backoffs = %v, want %v", waits, tt.waits)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies why an engine run failed.
+type ErrorKind string
+
+const (
+	ErrBinaryMissing   ErrorKind = "binary_missing"
+	ErrAuth            ErrorKind = "auth"
+	ErrRateLimit       ErrorKind = "rate_limit"
+	ErrNetwork         ErrorKind = "network"
+	ErrTimeout         ErrorKind = "timeout"
+	ErrInterrupted     ErrorKind = "interrupted"
+	ErrExit            ErrorKind = "exit"
+	ErrMalformedOutput ErrorKind = "malformed_output"
+	ErrBudget          ErrorKind = "budget"
+)
+
+// Transient reports whether a failure of this kind is worth retrying with
+// the same engine after a backoff.
+func (k ErrorKind) Transient() bool {
+	return k == ErrRateLimit || k == ErrNetwork
+}
+
+func (k ErrorKind) describe() string {
+	switch k {
+	case ErrBinaryMissing:
+		return "binary missing"
+	case ErrAuth:
+		return "authentication failed"
+	case ErrRateLimit:
+		return "rate limited"
+	case ErrNetwork:
+		return "network error"
+	case ErrTimeout:
+		return "timed out"
+	case ErrInterrupted:
+		return "interrupted"
+	case ErrMalformedOutput:
+		return "malformed output"
+	case ErrBudget:
+		return "over budget"
+	default:
+		return "failed"
+	}
+}
+
+// EngineError is returned by RunEngine for every engine failure.
+type EngineError struct {
+	Kind     ErrorKind
+	Engine   string
+	ExitCode int
+	Stderr   string
+	// Hint suggests how to fix the failure, e.g. how to log in again.
+	Hint string
+	Err  error
+}
+
+func (e *EngineError) Error() string {
+	msg := fmt.Sprintf("%s %s: %v", e.Engine, e.Kind.describe(), e.Err)
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += "\nstderr: " + stderr
+	}
+	return msg
+}
+
+func (e *EngineError) Unwrap() error { return e.Err }
+
+// ErrorKindOf returns the kind of an engine failure, or "" if err is not an
+// EngineError.
+func ErrorKindOf(err error) ErrorKind {
+	var ee *EngineError
+	if errors.As(err, &ee) {
+		return ee.Kind
+	}
+	return ""
+}
+
+// errorClassifier is implemented by engines that recognise their own
+// failure messages.
+type errorClassifier interface {
+	Classify(exitCode int, stderr string) ErrorKind
+}
+
+// hinter is implemented by engines that can suggest a fix for a failure.
+type hinter interface {
+	Hint(kind ErrorKind) string
+}
+
+// errorPatterns maps lower-case stderr substrings to a failure kind.
+// Patterns are phrases an engine or API prints for the failure, never bare
+// status codes or words that ordinary output contains.
+type errorPatterns map[ErrorKind][]string
+
+var commonErrorPatterns = errorPatterns{
+	ErrAuth: {
+		"unauthorized", "status 401", "http 401", "401 unauthorized", "authentication_error",
+		"authentication failed", "not logged in", "please log in", "login required",
+		"invalid api key", "invalid x-api-key", "api key not found", "token has expired",
+	},
+	ErrRateLimit: {
+		"rate limit", "rate_limit", "ratelimit", "status 429", "http 429", "429 too many",
+		"too many requests", "quota exceeded", "exceeded your current quota", "overloaded_error",
+		"status 529", "http 529", "usage limit",
+	},
+	ErrNetwork: {
+		"connection refused", "connection reset", "network is unreachable",
+		"no such host", "tls handshake timeout", "econnreset", "etimedout",
+	},
+}
+
+// classifyStderr matches stderr against engine-specific patterns first and
+// the common patterns second. Auth is checked before rate limits because
+// auth errors often mention quotas or limits in passing.
+func classifyStderr(exitCode int, stderr string, extra errorPatterns) ErrorKind {
+	if exitCode == 126 || exitCode == 127 {
+		return ErrBinaryMissing
+	}
+	lower := strings.ToLower(stderr)
+	for _, kind := range []ErrorKind{ErrAuth, ErrRateLimit, ErrNetwork} {
+		for _, p := range append(extra[kind], commonErrorPatterns[kind]...) {
+			if strings.Contains(lower, p) {
+				return kind
+			}
+		}
+	}
+	return ErrExit
+}
+
+// classifyError turns any error returned by Engine.Run into an EngineError
+// with its kind resolved from the context state, exit code and stderr.
+func classifyError(ctx context.Context, e Engine, err error) *EngineError {
+	var ee *EngineError
+	if !errors.As(err, &ee) {
+		ee = &EngineError{Kind: ErrExit, Err: err}
+	}
+	ee.Engine = e.Name()
+	switch {
+	case errors.Is(context.Cause(ctx), errRunBudget):
+		ee.Kind = ErrBudget
+		ee.Err = errRunBudget
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		ee.Kind = ErrTimeout
+	case ctx.Err() != nil:
+		ee.Kind = ErrInterrupted
+	case ee.Kind == ErrExit:
+		if c, ok := e.(errorClassifier); ok {
+			ee.Kind = c.Classify(ee.ExitCode, ee.Stderr)
+		} else {
+			ee.Kind = classifyStderr(ee.ExitCode, ee.Stderr, nil)
+		}
+	}
+	return ee
+}
@@ -0,0 +1,32 @@
+package wiki
+
+import "testing"
+
+func TestClassifyStderr(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   ErrorKind
+	}{
+		{"Error: 401 Unauthorized", ErrAuth},
+		{"request failed with status 401", ErrAuth},
+		{`{"type":"authentication_error","message":"invalid x-api-key"}`, ErrAuth},
+		{"API Error: 429 Too Many Requests", ErrRateLimit},
+		{`{"type":"rate_limit_error"}`, ErrRateLimit},
+		{`{"type":"overloaded_error","message":"Overloaded"}`, ErrRateLimit},
+		{"You exceeded your current quota", ErrRateLimit},
+		{"dial tcp: connection refused", ErrNetwork},
+		// Ordinary output that merely contains the old bare patterns.
+		{"wrote 401 lines to docs/auth.md", ErrExit},
+		{"updated pages 429 and 529", ErrExit},
+		{"documented the quota handling and authentication flow", ErrExit},
+		{"", ErrExit},
+	}
+	for _, tt := range tests {
+		if got := classifyStderr(1, tt.stderr, nil); got != tt.want {
+			t.Errorf("classifyStderr(%q) = %q, want %q", tt.stderr, got, tt.want)
+		}
+	}
+	if got := classifyStderr(127, "", nil); got != ErrBinaryMissing {
+		t.Errorf("exit 127 = %q, want %q", got, ErrBinaryMissing)
+	}
+}
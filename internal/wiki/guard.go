@@ -0,0 +1,191 @@
+package wiki
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// Agent CLIs run with write access to the whole repository; the prompt only
+// asks them to stay inside the wiki. The write guard snapshots the working
+// tree before a run and afterwards finds every path outside cfg.WikiPath and
+// repowiki's own .repowiki/ directory that was written during the run. It
+// warns about them, fails the run, or restores them, depending on
+// cfg.WriteGuard. Untracked files are never deleted.
+
+// errWriteViolation is returned when write_guard is "abort" and the engine
+// modified files outside the wiki.
+var errWriteViolation = errors.New("engine modified files outside the wiki")
+
+// treeSnapshot is the state of the working tree outside the wiki before a run.
+type treeSnapshot struct {
+	gitRoot string
+	cfg     *config.Config
+	head    string
+	// start is when the run began, truncated to the coarsest file time
+	// resolution; files last written before it weren't written by the run.
+	start time.Time
+	// dirty holds the pre-run content of paths that already differed from
+	// HEAD (the user's uncommitted work); nil means the file didn't exist.
+	dirty map[string][]byte
+}
+
+// snapshotTree records the pre-run state. It returns nil when the guard is
+// disabled.
+func snapshotTree(gitRoot string, cfg *config.Config) (*treeSnapshot, error) {
+	if cfg.WriteGuard == config.WriteGuardOff {
+		return nil, nil
+	}
+	head, _ := git.HeadCommit(gitRoot)
+	start := time.Now().Truncate(time.Second)
+	paths, err := git.DirtyPaths(gitRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+	snap := &treeSnapshot{gitRoot: gitRoot, cfg: cfg, head: head, start: start, dirty: map[string][]byte{}}
+	for _, p := range paths {
+		if snap.allowed(p) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(gitRoot, p))
+		if err != nil {
+			data = nil
+		}
+		snap.dirty[p] = data
+	}
+	return snap, nil
+}
+
+// allowed reports whether engines may write to path.
+func (s *treeSnapshot) allowed(path string) bool {
+	wiki := strings.TrimSuffix(filepath.ToSlash(filepath.Clean(s.cfg.WikiPath)), "/") + "/"
+	return strings.HasPrefix(path, wiki) || strings.HasPrefix(path, config.ConfigDir+"/")
+}
+
+// violations lists paths outside the wiki that were written during the run,
+// sorted. Paths are compared with the current HEAD, so a commit made during
+// the run (headMoved) doesn't make its files look modified.
+func (s *treeSnapshot) violations(headMoved bool) ([]string, error) {
+	paths, err := git.DirtyPaths(s.gitRoot)
+	if err != nil {
+		return nil, err
+	}
+	dirtyNow := map[string]bool{}
+	changed := map[string]bool{}
+	for _, p := range paths {
+		if s.allowed(p) {
+			continue
+		}
+		dirtyNow[p] = true
+		if _, wasDirty := s.dirty[p]; !wasDirty && s.writtenDuringRun(p) {
+			changed[p] = true
+		}
+	}
+	// The user's own uncommitted files must be exactly as they were, even
+	// if the engine reverted them to HEAD; but a file committed during the
+	// run is clean and was the user's doing.
+	for p, before := range s.dirty {
+		if headMoved && !dirtyNow[p] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.gitRoot, p))
+		exists := err == nil
+		if exists != (before != nil) || !bytes.Equal(data, before) {
+			changed[p] = true
+		}
+	}
+
+	result := make([]string, 0, len(changed))
+	for p := range changed {
+		result = append(result, p)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// writtenDuringRun reports whether a path that was clean before the run
+// was modified or deleted after it started. Files older than the run only
+// differ from HEAD because HEAD moved.
+func (s *treeSnapshot) writtenDuringRun(path string) bool {
+	info, err := os.Lstat(filepath.Join(s.gitRoot, path))
+	if err != nil {
+		return true
+	}
+	return !info.ModTime().Before(s.start)
+}
+
+// revert restores a path to its snapshot state. Untracked files are left in
+// place; it reports whether the path was restored.
+func (s *treeSnapshot) revert(path string) (bool, error) {
+	full := filepath.Join(s.gitRoot, path)
+	if before, wasDirty := s.dirty[path]; wasDirty && before != nil {
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return false, err
+		}
+		return true, os.WriteFile(full, before, 0644)
+	}
+	if !git.IsTracked(s.gitRoot, path) {
+		return false, nil
+	}
+	if _, wasDirty := s.dirty[path]; wasDirty {
+		// The user had deleted the tracked file.
+		return true, removeIfExists(full)
+	}
+	return true, git.RestoreFromHead(s.gitRoot, path)
+}
+
+// enforce checks the tree after a run and logs every violation. It returns
+// errWriteViolation if write_guard is "abort", and restores the paths if it
+// is "revert".
+func (s *treeSnapshot) enforce() error {
+	if s == nil {
+		return nil
+	}
+	head, _ := git.HeadCommit(s.gitRoot)
+	if head != s.head {
+		logf(s.gitRoot, "write guard: HEAD moved from %s to %s during the run; checking the tree against the new HEAD", s.head, head)
+	}
+
+	paths, err := s.violations(head != s.head)
+	if err != nil {
+		logf(s.gitRoot, "write guard: cannot check working tree: %v", err)
+		return nil
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	logf(s.gitRoot, "write guard: %d path(s) outside %s were modified during the run: %s", len(paths), s.cfg.WikiPath, strings.Join(paths, ", "))
+	switch s.cfg.WriteGuard {
+	case config.WriteGuardAbort:
+		return fmt.Errorf("%w: %s", errWriteViolation, strings.Join(paths, ", "))
+	case config.WriteGuardRevert:
+		for _, p := range paths {
+			restored, err := s.revert(p)
+			switch {
+			case err != nil:
+				logf(s.gitRoot, "write guard: failed to revert %s: %v", p, err)
+			case restored:
+				logf(s.gitRoot, "write guard: reverted %s", p)
+			default:
+				logf(s.gitRoot, "write guard: left untracked %s in place", p)
+			}
+		}
+	}
+	return nil
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
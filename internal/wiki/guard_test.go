@@ -0,0 +1,161 @@
+package wiki
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// newTestRepo creates a git repository holding files, committed once.
+func newTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	gitCmd(t, root, "init", "-q")
+	gitCmd(t, root, "config", "user.email", "test@example.com")
+	gitCmd(t, root, "config", "user.name", "test")
+	for p, content := range files {
+		writeTestFile(t, root, p, content)
+	}
+	gitCmd(t, root, "add", "-A")
+	gitCmd(t, root, "commit", "-q", "-m", "initial")
+	return root
+}
+
+func gitCmd(t *testing.T, root string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func writeTestFile(t *testing.T, root string, p string, content string) {
+	t.Helper()
+	full := filepath.Join(root, p)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, root string, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, p))
+	if err != nil {
+		return "<missing>"
+	}
+	return string(data)
+}
+
+// ageFile backdates a file so that it predates the snapshot.
+func ageFile(t *testing.T, root string, p string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(root, p), old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func guardConfig(mode string) *config.Config {
+	cfg := config.Default()
+	cfg.WriteGuard = mode
+	return cfg
+}
+
+func TestWriteGuardRevert(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"main.go": "package main\n", "user.go": "package main\n"})
+	writeTestFile(t, root, "user.go", "package main // user's work\n")
+	writeTestFile(t, root, "notes.txt", "user's untracked notes\n")
+	ageFile(t, root, "user.go")
+	ageFile(t, root, "notes.txt")
+
+	snap, err := snapshotTree(root, guardConfig(config.WriteGuardRevert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, root, "main.go", "package main // engine\n")
+	writeTestFile(t, root, "user.go", "package main // engine\n")
+	writeTestFile(t, root, "stray.txt", "engine scratch\n")
+	writeTestFile(t, root, ".qoder/repowiki/en/content/A.md", "# A\n")
+
+	paths, err := snap.violations(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"main.go", "stray.txt", "user.go"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] || paths[2] != want[2] {
+		t.Fatalf("violations = %v, want %v", paths, want)
+	}
+	if err := snap.enforce(); err != nil {
+		t.Fatal(err)
+	}
+	checks := map[string]string{
+		"main.go":                         "package main\n",
+		"user.go":                         "package main // user's work\n",
+		"notes.txt":                       "user's untracked notes\n",
+		"stray.txt":                       "engine scratch\n",
+		".qoder/repowiki/en/content/A.md": "# A\n",
+	}
+	for p, content := range checks {
+		if got := readTestFile(t, root, p); got != content {
+			t.Errorf("%s = %q, want %q", p, got, content)
+		}
+	}
+}
+
+func TestWriteGuardModes(t *testing.T) {
+	for _, mode := range []string{config.WriteGuardWarn, config.WriteGuardAbort} {
+		root := newTestRepo(t, map[string]string{"main.go": "package main\n"})
+		snap, err := snapshotTree(root, guardConfig(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, root, "main.go", "package main // engine\n")
+		err = snap.enforce()
+		if wantErr := mode == config.WriteGuardAbort; errors.Is(err, errWriteViolation) != wantErr {
+			t.Errorf("%s: enforce() = %v", mode, err)
+		}
+		if got := readTestFile(t, root, "main.go"); got != "package main // engine\n" {
+			t.Errorf("%s: main.go was changed to %q", mode, got)
+		}
+	}
+	if snap, _ := snapshotTree(t.TempDir(), guardConfig(config.WriteGuardOff)); snap != nil {
+		t.Error("off: snapshot taken")
+	}
+}
+
+func TestWriteGuardHeadMoved(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	writeTestFile(t, root, "a.go", "package a // v2\n")
+	gitCmd(t, root, "commit", "-q", "-am", "a v2")
+	ageFile(t, root, "a.go")
+	writeTestFile(t, root, "b.go", "package b // user's work\n")
+	ageFile(t, root, "b.go")
+
+	snap, err := snapshotTree(root, guardConfig(config.WriteGuardAbort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// During the run the user commits b.go and amends away the a.go
+	// change; neither file was written by the engine.
+	gitCmd(t, root, "reset", "-q", "--soft", "HEAD~1")
+	gitCmd(t, root, "commit", "-q", "-m", "b", "--", "b.go")
+
+	if err := snap.enforce(); err != nil {
+		t.Errorf("enforce() = %v, want nil", err)
+	}
+	writeTestFile(t, root, "b.go", "package b // engine\n")
+	if err := snap.enforce(); !errors.Is(err, errWriteViolation) {
+		t.Errorf("enforce() after an engine write = %v, want a violation", err)
+	}
+}
@@ -0,0 +1,101 @@
+package wiki
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// LineRange is an inclusive range of 1-based line numbers.
+type LineRange struct {
+	Start, End int
+}
+
+// Overlaps reports whether the ranges share at least one line.
+func (r LineRange) Overlaps(o LineRange) bool {
+	return r.Start <= o.End && o.Start <= r.End
+}
+
+// ParseLineRange parses a line_range from the metadata ("10-20", or "42"
+// for a single line) or a link anchor ("L10-L20", "L42").
+func ParseLineRange(s string) (LineRange, bool) {
+	from, to, found := strings.Cut(strings.TrimSpace(s), "-")
+	start, err := strconv.Atoi(strings.TrimPrefix(from, "L"))
+	if err != nil || start < 1 {
+		return LineRange{}, false
+	}
+	end := start
+	if found {
+		if end, err = strconv.Atoi(strings.TrimPrefix(to, "L")); err != nil || end < start {
+			return LineRange{}, false
+		}
+	}
+	return LineRange{Start: start, End: end}, true
+}
+
+// hunkRange is the lines of the old file a hunk touches; an insertion
+// touches the lines on either side of it.
+func hunkRange(h git.Hunk) LineRange {
+	if h.Lines == 0 {
+		return LineRange{Start: h.Start, End: h.Start + 1}
+	}
+	return LineRange{Start: h.Start, End: h.Start + h.Lines - 1}
+}
+
+// changedLines returns, per file, the lines changed since the last
+// processed commit, numbered as in the wiki's last run. Files whose changes
+// can't be placed by line, such as binary files, are left out; a renamed
+// file is keyed by its new path.
+func changedLines(gitRoot string, cfg *config.Config, commitHash string, changes []git.FileChange) map[string][]LineRange {
+	from, to := commitRange(cfg, commitHash)
+	changed := map[string][]LineRange{}
+	for _, c := range changes {
+		hunks, err := git.FileHunks(gitRoot, from, to, diffPaths(c)...)
+		if err != nil || len(hunks) == 0 {
+			continue
+		}
+		for _, h := range hunks {
+			changed[c.Path] = append(changed[c.Path], hunkRange(h))
+		}
+	}
+	return changed
+}
+
+// snippetLines returns, per file, the line ranges recorded in the metadata.
+func snippetLines(gitRoot string, cfg *config.Config) map[string][]LineRange {
+	recorded := map[string][]LineRange{}
+	meta, err := loadMetadata(gitRoot, cfg)
+	if err != nil {
+		return recorded
+	}
+	for _, s := range meta.CodeSnippets {
+		if r, ok := ParseLineRange(s.LineRange); ok {
+			recorded[s.Path] = append(recorded[s.Path], r)
+		}
+	}
+	return recorded
+}
+
+// touchesDocumented reports whether changes to a file reach the lines a
+// page documents: the ranges the page cites, or else every range recorded
+// for the file. Without either, or without line information about the
+// change, any change counts.
+func touchesDocumented(cited []LineRange, recorded []LineRange, changed []LineRange) bool {
+	documented := cited
+	if len(documented) == 0 {
+		documented = recorded
+	}
+	if len(documented) == 0 || len(changed) == 0 {
+		return true
+	}
+	for _, c := range changed {
+		for _, d := range documented {
+			if c.Overlaps(d) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package wiki
+
+import (
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		in   string
+		want LineRange
+		ok   bool
+	}{
+		{"10-20", LineRange{10, 20}, true},
+		{"42", LineRange{42, 42}, true},
+		{"L10-L20", LineRange{10, 20}, true},
+		{"L7", LineRange{7, 7}, true},
+		{" 3-3 ", LineRange{3, 3}, true},
+		{"20-10", LineRange{}, false},
+		{"0-5", LineRange{}, false},
+		{"L10-", LineRange{}, false},
+		{"overview", LineRange{}, false},
+		{"", LineRange{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLineRange(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseLineRange(%q) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHunkRangeOverlaps(t *testing.T) {
+	documented := LineRange{10, 20}
+	tests := []struct {
+		hunk git.Hunk
+		want bool
+	}{
+		{git.Hunk{Start: 15, Lines: 2}, true},
+		{git.Hunk{Start: 1, Lines: 9}, false},
+		{git.Hunk{Start: 1, Lines: 10}, true},
+		{git.Hunk{Start: 21, Lines: 3}, false},
+		// Insertions touch the lines on either side.
+		{git.Hunk{Start: 9, Lines: 0}, true},
+		{git.Hunk{Start: 20, Lines: 0}, true},
+		{git.Hunk{Start: 21, Lines: 0}, false},
+	}
+	for _, tt := range tests {
+		if got := hunkRange(tt.hunk).Overlaps(documented); got != tt.want {
+			t.Errorf("hunk %+v overlaps %+v = %v, want %v", tt.hunk, documented, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package wiki
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+type codeSnippet struct {
+	ID          string `json:"id"`
+	Path        string `json:"path"`
+	LineRange   string `json:"line_range"`
+	GmtCreate   string `json:"gmt_create,omitempty"`
+	GmtModified string `json:"gmt_modified,omitempty"`
+}
+
+type metadata struct {
+	CodeSnippets []codeSnippet `json:"code_snippets"`
+}
+
+// metadataMu serializes the metadata updates of the built-in engines,
+// whose runs split generation may run in parallel. Agent engines write to
+// a file per unit instead; see unitMetadataFile.
+var metadataMu sync.Mutex
+
+func metadataPath(gitRoot string, cfg *config.Config) string {
+	return filepath.Join(gitRoot, cfg.WikiPath, cfg.Language, "meta", "repowiki-metadata.json")
+}
+
+func loadMetadata(gitRoot string, cfg *config.Config) (*metadata, error) {
+	data, err := os.ReadFile(metadataPath(gitRoot, cfg))
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func saveMetadata(gitRoot string, cfg *config.Config, meta *metadata) error {
+	path := metadataPath(gitRoot, cfg)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// setSnippets replaces the snippets recorded for path with the given line
+// ranges, keeping the creation time of ranges that were already present.
+func (m *metadata) setSnippets(path string, lineRanges []string) {
+	m.setSnippetsAt(path, lineRanges, time.Now().UTC().Format(time.RFC3339))
+}
+
+// setSnippetsAt is setSnippets with an explicit timestamp; "" omits the
+// timestamps, which keeps the file reproducible.
+func (m *metadata) setSnippetsAt(path string, lineRanges []string, now string) {
+	existing := map[string]codeSnippet{}
+	kept := m.CodeSnippets[:0]
+	for _, s := range m.CodeSnippets {
+		if s.Path == path {
+			existing[s.ID] = s
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	m.CodeSnippets = kept
+
+	for _, lr := range lineRanges {
+		sum := md5.Sum([]byte(path + ":" + lr))
+		s := codeSnippet{ID: hex.EncodeToString(sum[:]), Path: path, LineRange: lr, GmtCreate: now, GmtModified: now}
+		if old, ok := existing[s.ID]; ok && old.GmtCreate != "" {
+			s.GmtCreate = old.GmtCreate
+		}
+		m.CodeSnippets = append(m.CodeSnippets, s)
+	}
+}
+
+// renamePath moves the snippets of oldPath to newPath, with IDs derived
+// from the new path. It reports whether there were any.
+func (m *metadata) renamePath(oldPath string, newPath string) bool {
+	moved := false
+	for i, s := range m.CodeSnippets {
+		if s.Path != oldPath {
+			continue
+		}
+		sum := md5.Sum([]byte(newPath + ":" + s.LineRange))
+		m.CodeSnippets[i].Path, m.CodeSnippets[i].ID = newPath, hex.EncodeToString(sum[:])
+		moved = true
+	}
+	return moved
+}
@@ -0,0 +1,199 @@
+package wiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/lockfile"
+	"github.com/ikrasnodymov/repowiki/internal/pathmatch"
+)
+
+// Two-phase generation first asks the engine for a page outline, saved to
+// .repowiki/outline.json for the team to review and edit, and then writes
+// each page of the outline in its own engine run. The outline, not the
+// model, decides the wiki's structure, so it stays stable across
+// regenerations.
+
+// Outline is the list of wiki pages two-phase generation writes.
+type Outline struct {
+	Pages []OutlinePage `json:"pages"`
+}
+
+// OutlinePage is one page of an Outline.
+type OutlinePage struct {
+	// Path is relative to the wiki content directory, e.g.
+	// "Core Features/Hooks.md".
+	Path        string `json:"path"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// Sources are repository paths or gitignore-style globs of the files
+	// the page documents.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// LoadOutline reads .repowiki/outline.json.
+func LoadOutline(gitRoot string) (*Outline, error) {
+	data, err := os.ReadFile(config.OutlinePath(gitRoot))
+	if err != nil {
+		return nil, err
+	}
+	var o Outline
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", config.OutlineFile, err)
+	}
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", config.OutlineFile, err)
+	}
+	return &o, nil
+}
+
+func saveOutline(gitRoot string, o *Outline) error {
+	if err := os.MkdirAll(config.Dir(gitRoot), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(config.OutlinePath(gitRoot), append(data, '\n'), 0644)
+}
+
+// Validate checks that every page has a unique markdown path inside the
+// content directory and that its source globs are valid.
+func (o *Outline) Validate() error {
+	if len(o.Pages) == 0 {
+		return errors.New("outline has no pages")
+	}
+	seen := map[string]bool{}
+	for i, p := range o.Pages {
+		if p.Path == "" {
+			return fmt.Errorf("page %d has no path", i+1)
+		}
+		clean := path.Clean(p.Path)
+		if clean != p.Path || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("page %q: path must be a clean path relative to the content directory", p.Path)
+		}
+		if !strings.HasSuffix(clean, ".md") {
+			return fmt.Errorf("page %q: path must end in .md", p.Path)
+		}
+		if seen[clean] {
+			return fmt.Errorf("page %q is listed twice", p.Path)
+		}
+		seen[clean] = true
+		for _, g := range p.Sources {
+			if !pathmatch.Valid(g) {
+				return fmt.Errorf("page %q: invalid source glob %q", p.Path, g)
+			}
+		}
+	}
+	return nil
+}
+
+// GenerateOutline asks the engine for a page outline and saves it to
+// .repowiki/outline.json, replacing any existing outline.
+func GenerateOutline(ctx context.Context, gitRoot string, cfg *config.Config, opts Options) (*Outline, error) {
+	if err := checkConfig(gitRoot, cfg); err != nil {
+		return nil, err
+	}
+	if err := lockfile.Acquire(gitRoot); err != nil {
+		return nil, fmt.Errorf("cannot acquire lock: %w", err)
+	}
+	defer lockfile.Release(gitRoot)
+
+	if err := checkBudget(gitRoot, cfg, opts); err != nil {
+		return nil, err
+	}
+	snap, err := snapshotTree(gitRoot, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("outline generation failed: %w", err)
+	}
+	o, err := generateOutline(ctx, gitRoot, cfg, opts)
+	if gErr := snap.enforce(); gErr != nil {
+		return nil, fmt.Errorf("outline generation aborted: %w", gErr)
+	}
+	return o, err
+}
+
+func generateOutline(ctx context.Context, gitRoot string, cfg *config.Config, opts Options) (*Outline, error) {
+	logf(gitRoot, "generating wiki outline")
+	prompt, err := RenderPrompt(gitRoot, newPromptData(cfg, ModeOutline))
+	if err != nil {
+		return nil, fmt.Errorf("outline generation failed: %w", err)
+	}
+	res, err := RunEngine(ctx, cfg, gitRoot, Request{Mode: ModeOutline, Prompt: prompt}, opts)
+	if err != nil {
+		logf(gitRoot, "engine failed: %v", err)
+		return nil, fmt.Errorf("outline generation failed: %w", err)
+	}
+	o, err := parseOutline(res.Output)
+	if err != nil {
+		logf(gitRoot, "engine %s returned an unusable outline: %v", res.Engine, err)
+		return nil, fmt.Errorf("outline generation failed: %w", err)
+	}
+	if err := saveOutline(gitRoot, o); err != nil {
+		return nil, fmt.Errorf("failed to save outline: %w", err)
+	}
+	logf(gitRoot, "engine %s proposed an outline of %d pages%s", res.Engine, len(o.Pages), res.Usage.summary())
+	return o, nil
+}
+
+// parseOutline extracts the outline JSON object from an engine's answer,
+// which may wrap it in prose or a code fence.
+func parseOutline(output string) (*Outline, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON object in engine output")
+	}
+	var o Outline
+	if err := json.Unmarshal([]byte(output[start:end+1]), &o); err != nil {
+		return nil, fmt.Errorf("invalid outline JSON: %w", err)
+	}
+	for i, p := range o.Pages {
+		if p.Path != "" {
+			o.Pages[i].Path = strings.TrimPrefix(path.Clean(p.Path), "./")
+		}
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// generateFromOutline writes every page of the outline, creating the outline
+// first if there is none. Each page is a separate engine run; the result
+// sums their usage.
+func generateFromOutline(ctx context.Context, gitRoot string, cfg *config.Config, opts Options) (*Result, error) {
+	o, err := LoadOutline(gitRoot)
+	if errors.Is(err, os.ErrNotExist) {
+		o, err = generateOutline(ctx, gitRoot, cfg, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	units, err := pageUnits(gitRoot, cfg, o)
+	if err != nil {
+		return nil, fmt.Errorf("wiki generation failed: %w", err)
+	}
+	return runUnits(ctx, gitRoot, cfg, units, opts)
+}
+
+// matchSources lists the tracked files matched by a page's or section's
+// sources; nil (the whole repository) when it has none.
+func matchSources(cfg *config.Config, sources []string, files []string) []string {
+	if len(sources) == 0 {
+		return nil
+	}
+	matched := []string{}
+	for _, f := range files {
+		if !isExcluded(f, cfg.ExcludedPaths) && pathmatch.MatchAny(sources, f) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
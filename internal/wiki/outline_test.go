@@ -0,0 +1,93 @@
+package wiki
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseOutline(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		paths  []string // nil when parsing fails
+		err    string
+	}{
+		{
+			name:   "bare JSON",
+			output: `{"pages":[{"path":"Overview.md","title":"Overview","sources":["README.md"]}]}`,
+			paths:  []string{"Overview.md"},
+		},
+		{
+			name:   "fenced",
+			output: "```json\n{\"pages\": [\n  {\"path\": \"Overview.md\", \"title\": \"Overview\"},\n  {\"path\": \"Core/Hooks.md\", \"title\": \"Hooks\", \"sources\": [\"internal/hook/**\"]}\n]}\n```",
+			paths:  []string{"Overview.md", "Core/Hooks.md"},
+		},
+		{
+			name:   "prose around",
+			output: "Here is the outline:\n\n{\"pages\":[{\"path\":\"Overview.md\",\"title\":\"Overview\"}]}\n\nLet me know if it needs changes.",
+			paths:  []string{"Overview.md"},
+		},
+		{
+			name:   "paths cleaned",
+			output: `{"pages":[{"path":"./Overview.md","title":"Overview"},{"path":"Core//Engines/./Local.md","title":"Local"},{"path":"Core/x/../Hooks.md","title":"Hooks"}]}`,
+			paths:  []string{"Overview.md", "Core/Engines/Local.md", "Core/Hooks.md"},
+		},
+		{name: "no JSON", output: "I could not read the repository.", err: "no JSON object"},
+		{name: "invalid JSON", output: `{"pages":[{"path":"Overview.md",}]}`, err: "invalid outline JSON"},
+		{name: "no pages", output: `{"pages":[]}`, err: "no pages"},
+		{name: "empty path", output: `{"pages":[{"title":"Overview"}]}`, err: "has no path"},
+		{name: "duplicate path", output: `{"pages":[{"path":"Overview.md"},{"path":"./Overview.md"}]}`, err: "listed twice"},
+		{name: "not markdown", output: `{"pages":[{"path":"Overview.txt"}]}`, err: "must end in .md"},
+		{name: "directory", output: `{"pages":[{"path":"Core/"}]}`, err: "must end in .md"},
+		{name: "escape", output: `{"pages":[{"path":"../README.md"}]}`, err: "clean path relative"},
+		{name: "escape after cleaning", output: `{"pages":[{"path":"Core/../../README.md"}]}`, err: "clean path relative"},
+		{name: "absolute", output: `{"pages":[{"path":"/etc/motd.md"}]}`, err: "clean path relative"},
+		{name: "bad glob", output: `{"pages":[{"path":"Overview.md","sources":["src/[a-"]}]}`, err: "invalid source glob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := parseOutline(tt.output)
+			if tt.paths == nil {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseOutline() error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, p := range o.Pages {
+				paths = append(paths, p.Path)
+			}
+			if !slices.Equal(paths, tt.paths) {
+				t.Errorf("page paths = %q, want %q", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestOutlineValidate(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"Overview.md", true},
+		{"Core Features/Hooks.md", true},
+		// Outlines edited by hand are not cleaned.
+		{"./Overview.md", false},
+		{"Core//Hooks.md", false},
+		{"Core/../Hooks.md", false},
+		{"../Hooks.md", false},
+		{"..", false},
+		{"/Hooks.md", false},
+		{"Hooks", false},
+	}
+	for _, tt := range tests {
+		o := &Outline{Pages: []OutlinePage{{Path: tt.path, Title: "Page"}}}
+		if err := o.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate() of %q = %v, want ok %v", tt.path, err, tt.ok)
+		}
+	}
+}
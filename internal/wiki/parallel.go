@@ -0,0 +1,270 @@
+package wiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// Split generation writes the wiki in several engine runs, one per section
+// of the configured outline or per page of .repowiki/outline.json, up to
+// cfg.Concurrency at a time. Units that fail are retried once after the
+// others finished; whatever succeeded is committed together.
+
+// metaPartsDir holds the metadata written by parallel runs, one file per
+// unit, until it is merged into repowiki-metadata.json. Concurrent agents
+// editing the same JSON file would overwrite each other's entries.
+const metaPartsDir = "parts"
+
+// genUnit is one independently generated part of the wiki.
+type genUnit struct {
+	name string
+	req  Request
+}
+
+// SplitError reports the units of a split generation that still failed
+// after their retry. The others were written.
+type SplitError struct {
+	Failed []string
+	Total  int
+	Unit   string // "section" or "page"
+	Err    error
+}
+
+func (e *SplitError) Error() string {
+	return fmt.Sprintf("%d of %d %ss failed (%s): %v", len(e.Failed), e.Total, e.Unit, strings.Join(e.Failed, ", "), e.Err)
+}
+
+// unitKind names the units in messages: "page" for the pages of an
+// outline, "section" otherwise.
+func unitKind(units []genUnit) string {
+	if len(units) > 0 && units[0].req.Mode == ModePage {
+		return "page"
+	}
+	return "section"
+}
+
+func (e *SplitError) Unwrap() error { return e.Err }
+
+// sectionUnits makes one unit per section of the configured outline.
+func sectionUnits(gitRoot string, cfg *config.Config) ([]genUnit, error) {
+	files, err := git.ListFiles(gitRoot)
+	if err != nil {
+		return nil, err
+	}
+	sections := cfg.WikiSections()
+	units := make([]genUnit, 0, len(sections))
+	for i, sec := range sections {
+		data := newPromptData(cfg, ModeSection)
+		data.Section = &sec
+		data.MetadataFile = unitMetadataFile(cfg, data.MetadataFile, i)
+		prompt, err := RenderPrompt(gitRoot, data)
+		if err != nil {
+			return nil, err
+		}
+		page := sec.Title + ".md"
+		if sec.Directory {
+			page = sec.Title + "/"
+		}
+		req := Request{Mode: ModeSection, Prompt: prompt, Page: page, Files: matchSources(cfg, sec.Sources, files)}
+		units = append(units, genUnit{name: page, req: req})
+	}
+	return units, nil
+}
+
+// pageUnits makes one unit per page of the outline.
+func pageUnits(gitRoot string, cfg *config.Config, o *Outline) ([]genUnit, error) {
+	files, err := git.ListFiles(gitRoot)
+	if err != nil {
+		return nil, err
+	}
+	units := make([]genUnit, 0, len(o.Pages))
+	for i, page := range o.Pages {
+		data := newPromptData(cfg, ModePage)
+		data.Outline = o
+		data.Page = &page
+		data.MetadataFile = unitMetadataFile(cfg, data.MetadataFile, i)
+		prompt, err := RenderPrompt(gitRoot, data)
+		if err != nil {
+			return nil, err
+		}
+		req := Request{Mode: ModePage, Prompt: prompt, Page: page.Path, Files: matchSources(cfg, page.Sources, files)}
+		units = append(units, genUnit{name: page.Path, req: req})
+	}
+	return units, nil
+}
+
+// unitMetadataFile is where unit i records its snippets: the shared
+// metadata file when units run one at a time, a file of its own otherwise.
+func unitMetadataFile(cfg *config.Config, shared string, i int) string {
+	if cfg.Workers() == 1 {
+		return shared
+	}
+	return fmt.Sprintf("%s/%s/meta/%s/%03d.json", cfg.WikiPath, cfg.Language, metaPartsDir, i+1)
+}
+
+// runUnits runs the units on a pool of cfg.Workers() goroutines, then
+// retries the failed ones once, and merges the metadata they wrote. The
+// result sums the usage of all runs; the error is a *SplitError when some
+// units succeeded.
+func runUnits(ctx context.Context, gitRoot string, cfg *config.Config, units []genUnit, opts Options) (*Result, error) {
+	kind := unitKind(units)
+	total := &Result{}
+	engines := map[string]bool{}
+	pending := units
+	var failed []genUnit
+	var errs []error
+	for pass := 1; pass <= 2 && len(pending) > 0; pass++ {
+		if pass > 1 {
+			if err := checkBudget(gitRoot, cfg, opts); err != nil {
+				break
+			}
+			logf(gitRoot, "retrying %d failed %s(s)", len(pending), kind)
+		}
+		failed, errs = nil, nil
+		for i, r := range runPool(ctx, gitRoot, cfg, pending, opts) {
+			if r.err != nil {
+				failed = append(failed, pending[i])
+				errs = append(errs, fmt.Errorf("%s: %w", pending[i].name, r.err))
+				continue
+			}
+			engines[r.res.Engine] = true
+			total.Usage.merge(r.res.Usage)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		pending = retryable(failed, errs)
+	}
+	if err := mergeMetadataParts(gitRoot, cfg); err != nil {
+		logf(gitRoot, "failed to merge metadata: %v", err)
+	}
+
+	total.Engine = strings.Join(sortedKeys(engines), ",")
+	total.Output = fmt.Sprintf("wrote %d of %d %ss", len(units)-len(failed), len(units), kind)
+	if len(failed) == 0 {
+		return total, nil
+	}
+	err := errors.Join(errs...)
+	if len(failed) == len(units) {
+		return nil, err
+	}
+	names := make([]string, len(failed))
+	for i, u := range failed {
+		names[i] = u.name
+	}
+	return total, &SplitError{Failed: names, Total: len(units), Unit: kind, Err: err}
+}
+
+// retryable drops units that would fail the same way again: runs stopped
+// by the per-run budget or by the user.
+func retryable(failed []genUnit, errs []error) []genUnit {
+	var units []genUnit
+	for i, u := range failed {
+		if kind := ErrorKindOf(errs[i]); kind != ErrBudget && kind != ErrInterrupted {
+			units = append(units, u)
+		}
+	}
+	return units
+}
+
+type unitResult struct {
+	res *Result
+	err error
+}
+
+// runPool runs every unit and returns their results in unit order. Units
+// not started before ctx is cancelled fail with its error.
+func runPool(ctx context.Context, gitRoot string, cfg *config.Config, units []genUnit, opts Options) []unitResult {
+	results := make([]unitResult, len(units))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(cfg.Workers(), len(units)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				logf(gitRoot, "writing %s (%d/%d)", units[i].name, i+1, len(units))
+				res, err := RunEngine(ctx, cfg, gitRoot, units[i].req, opts)
+				if err != nil {
+					logf(gitRoot, "engine failed on %s: %v", units[i].name, err)
+				}
+				results[i] = unitResult{res: res, err: err}
+			}
+		}()
+	}
+	for i := range units {
+		if ctx.Err() != nil {
+			results[i].err = &EngineError{Kind: ErrInterrupted, Err: ctx.Err()}
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// mergeMetadataParts moves the snippets of the per-unit metadata files into
+// repowiki-metadata.json. The parts replace all earlier snippets of the
+// files they cover; snippets of other files are kept.
+func mergeMetadataParts(gitRoot string, cfg *config.Config) error {
+	dir := filepath.Join(filepath.Dir(metadataPath(gitRoot, cfg)), metaPartsDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	byPath := map[string][]codeSnippet{}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var part metadata
+		if err := json.Unmarshal(data, &part); err != nil {
+			logf(gitRoot, "skipping unreadable metadata part %s: %v", e.Name(), err)
+			continue
+		}
+		for _, s := range part.CodeSnippets {
+			byPath[s.Path] = append(byPath[s.Path], s)
+		}
+	}
+	if len(byPath) == 0 {
+		return nil
+	}
+
+	meta, err := loadMetadata(gitRoot, cfg)
+	if err != nil {
+		meta = &metadata{}
+	}
+	kept := meta.CodeSnippets[:0]
+	for _, s := range meta.CodeSnippets {
+		if _, replaced := byPath[s.Path]; !replaced {
+			kept = append(kept, s)
+		}
+	}
+	meta.CodeSnippets = kept
+	for _, p := range sortedKeys(byPath) {
+		seen := map[string]bool{}
+		for _, s := range byPath[p] {
+			if s.ID == "" || !seen[s.ID] {
+				seen[s.ID] = true
+				meta.CodeSnippets = append(meta.CodeSnippets, s)
+			}
+		}
+	}
+	return saveMetadata(gitRoot, cfg, meta)
+}
@@ -0,0 +1,135 @@
+package wiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// failFake sets the fake engine's failure rules for the test, starting
+// their counts afresh.
+func failFake(t *testing.T, rules string) {
+	t.Helper()
+	t.Setenv(fakeFailEnv, rules)
+	fakeFailMu.Lock()
+	fakeFailCounts = map[string]int{}
+	fakeFailMu.Unlock()
+}
+
+func TestRunUnits(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		fail   string
+		failed []string // nil when all units are written
+		unit   string
+	}{
+		{name: "all written", mode: ModeSection},
+		{name: "retry pass succeeds", mode: ModeSection, fail: "network@A.md*1"},
+		{name: "retry pass fails", mode: ModeSection, fail: "network@A.md*2", failed: []string{"A.md"}, unit: "section"},
+		{name: "budget not retried", mode: ModeSection, fail: "budget@A.md*1", failed: []string{"A.md"}, unit: "section"},
+		{name: "pages", mode: ModePage, fail: "auth@B.md", failed: []string{"B.md"}, unit: "page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failFake(t, tt.fail)
+			root := newTestRepo(t, map[string]string{"a/x.go": "package a\n", "b/y.go": "package b\n"})
+			cfg := config.Default()
+			cfg.Engine = config.EngineFake
+			cfg.MaxRetries = 0
+			cfg.Concurrency = 2
+			units := []genUnit{
+				{name: "A.md", req: Request{Mode: tt.mode, Page: "A.md", Files: []string{"a/x.go"}}},
+				{name: "B.md", req: Request{Mode: tt.mode, Page: "B.md", Files: []string{"b/y.go"}}},
+			}
+
+			res, err := runUnits(context.Background(), root, cfg, units, Options{})
+			if res == nil {
+				t.Fatalf("runUnits() result = nil, err = %v", err)
+			}
+			var split *SplitError
+			if tt.failed == nil {
+				if err != nil {
+					t.Fatalf("runUnits() = %v, want nil", err)
+				}
+			} else if !errors.As(err, &split) {
+				t.Fatalf("runUnits() = %v, want a *SplitError", err)
+			} else if !slices.Equal(split.Failed, tt.failed) || split.Total != 2 || split.Unit != tt.unit {
+				t.Errorf("SplitError = %+v, want failed %v of 2 %ss", split, tt.failed, tt.unit)
+			}
+			for _, u := range units {
+				_, statErr := os.Stat(filepath.Join(root, cfg.WikiPath, cfg.Language, "content", u.name))
+				if written := !slices.Contains(tt.failed, u.name); written != (statErr == nil) {
+					t.Errorf("%s: written = %v, stat error = %v", u.name, written, statErr)
+				}
+			}
+		})
+	}
+}
+
+func TestRunUnitsAllFailed(t *testing.T) {
+	failFake(t, "auth")
+	root := newTestRepo(t, map[string]string{"a/x.go": "package a\n"})
+	cfg := config.Default()
+	cfg.Engine = config.EngineFake
+	units := []genUnit{{name: "A.md", req: Request{Mode: ModeSection, Page: "A.md"}}}
+
+	res, err := runUnits(context.Background(), root, cfg, units, Options{})
+	var split *SplitError
+	if res != nil || err == nil || errors.As(err, &split) {
+		t.Errorf("runUnits() = %v, %v; want no result and a plain error", res, err)
+	}
+	if ErrorKindOf(err) != ErrAuth {
+		t.Errorf("error kind = %q, want %q", ErrorKindOf(err), ErrAuth)
+	}
+}
+
+func TestMergeMetadataParts(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.Default()
+	meta := &metadata{}
+	meta.setSnippetsAt("kept.go", []string{"1-5"}, "")
+	meta.setSnippetsAt("x.go", []string{"1-100"}, "")
+	if err := saveMetadata(root, cfg, meta); err != nil {
+		t.Fatal(err)
+	}
+	part := func(path string, ranges ...string) string {
+		m := &metadata{}
+		m.setSnippetsAt(path, ranges, "")
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	parts := filepath.Join(cfg.WikiPath, cfg.Language, "meta", metaPartsDir)
+	writeTestFile(t, root, filepath.Join(parts, "001.json"), part("x.go", "1-10", "20-30"))
+	writeTestFile(t, root, filepath.Join(parts, "002.json"), part("x.go", "20-30"))
+	writeTestFile(t, root, filepath.Join(parts, "003.json"), part("y.go", "1-2"))
+	writeTestFile(t, root, filepath.Join(parts, "004.json"), "{not json")
+
+	if err := mergeMetadataParts(root, cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadMetadata(root, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ranges []string
+	for _, s := range got.CodeSnippets {
+		ranges = append(ranges, s.Path+":"+s.LineRange)
+	}
+	want := []string{"kept.go:1-5", "x.go:1-10", "x.go:20-30", "y.go:1-2"}
+	if !slices.Equal(ranges, want) {
+		t.Errorf("merged snippets = %v, want %v", ranges, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, parts)); !os.IsNotExist(err) {
+		t.Errorf("parts directory left behind: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+const (
+	probePrompt  = "This is a connectivity check from repowiki. Do not use any tools. Reply with the single word OK."
+	probeTimeout = 2 * time.Minute
+)
+
+// prober is implemented by engines whose normal Run is too expensive to use
+// as a probe.
+type prober interface {
+	Probe(ctx context.Context, inv *Invocation) error
+}
+
+// ProbeEngine runs the named engine once with a trivial prompt to check that
+// it starts and is authenticated. The outcome replaces the engine's recorded
+// failure state, so a fixed login stops being reported by `repowiki status`.
+// Agent CLIs run in the repository even for a probe, so the write guard
+// checks it like any other run.
+func ProbeEngine(ctx context.Context, cfg *config.Config, gitRoot string, name string) error {
+	e, err := LookupEngine(name)
+	if err != nil {
+		return err
+	}
+	ecfg := *EngineConfig(cfg, name)
+	ecfg.MaxTurns = 1
+
+	snap, err := snapshotTree(gitRoot, &ecfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	inv := &Invocation{Cfg: &ecfg, GitRoot: gitRoot, Request: Request{Mode: ModeProbe, Prompt: probePrompt}}
+	if p, ok := e.(prober); ok {
+		err = p.Probe(ctx, inv)
+	} else {
+		_, err = e.Run(ctx, inv)
+	}
+	if err != nil {
+		ee := classifyError(ctx, e, err)
+		if h, ok := e.(hinter); ok && ee.Hint == "" {
+			ee.Hint = h.Hint(ee.Kind)
+		}
+		err = ee
+	}
+	recordEngineStatus(gitRoot, name, err)
+	if gErr := snap.enforce(); gErr != nil && err == nil {
+		return fmt.Errorf("probe aborted: %w", gErr)
+	}
+	return err
+}
@@ -1,76 +1,216 @@
 package wiki
 
 import (
+	"bytes"
+	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
 )
 
-func BuildFullGeneratePrompt(cfg *config.Config) string {
-	return fmt.Sprintf(`You are a technical documentation specialist. Generate a comprehensive repository wiki for this project.
+// Built-in prompt templates, one per mode. A file named <mode>.tmpl in
+// .repowiki/prompts/ replaces the built-in template of that mode.
+//
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
 
-OUTPUT REQUIREMENTS:
-- Create documentation files in %s/%s/content/ directory
-- Create a metadata file at %s/%s/meta/repowiki-metadata.json
-- Each markdown file must follow this structure:
-  1. Title as H1 heading
-  2. <cite> block listing referenced source files with format: [filename](file://path/to/file)
-  3. Table of Contents with anchor links
-  4. Detailed content with code examples from the actual source
-  5. Mermaid diagrams for architecture where appropriate
+// PromptData is what prompt templates can refer to.
+type PromptData struct {
+	// Mode is the request mode the prompt is rendered for.
+	Mode string
+	// WikiPath is the wiki root relative to the repository, e.g. ".qoder/repowiki".
+	WikiPath string
+	// Language is the wiki language, e.g. "en".
+	Language string
+	// ContentDir is the directory holding the wiki pages.
+	ContentDir string
+	// MetadataFile is the path of repowiki-metadata.json.
+	MetadataFile string
+	// Sections is the wiki outline from config, or the default one.
+	Sections []config.Section
+	// ChangedFiles are the added, modified and renamed source files of an
+	// incremental update, by their new path.
+	ChangedFiles []string
+	// RenamedFiles are the renamed source files. Their citations and
+	// metadata already use the new path.
+	RenamedFiles []RenamedFile
+	// DeletedFiles are the deleted source files, with the pages citing them.
+	DeletedFiles []DeletedFile
+	// AffectedSections are the wiki sections and pages likely affected by
+	// ChangedFiles.
+	AffectedSections []string
+	// Diffs are the unified diffs of ChangedFiles, cut at diff_max_bytes.
+	Diffs []FileDiff
+	// Commits are the commits the update covers, oldest first.
+	Commits []CommitInfo
+	// Symbols are the exported Go identifiers the changes added, removed
+	// or redeclared.
+	Symbols []SymbolChange
+	// Outline is the page outline of two-phase generation, and Page the
+	// page being written (page mode only).
+	Outline *Outline
+	Page    *OutlinePage
+	// Section is the section being written (section mode only).
+	Section *config.Section
+}
 
-WIKI STRUCTURE — create these files/directories:
-- System Overview.md — project purpose, high-level architecture
-- Technology Stack.md — languages, frameworks, key dependencies
-- Getting Started.md — setup, installation, running
-- Backend Architecture/ — server structure, API design, database, etc.
-- Frontend Architecture/ — UI components, state management, etc.
-- Core Features/ — each major feature documented individually
-- API Reference/ — endpoints, request/response formats
-- Configuration Management.md — environment variables, config files
+var promptFuncs = template.FuncMap{
+	"join":   strings.Join,
+	"indent": indent,
+}
 
-METADATA FORMAT for repowiki-metadata.json:
-{
-  "code_snippets": [
-    {
-      "id": "<md5 hash>",
-      "path": "relative/path/to/file",
-      "line_range": "1-100",
-      "gmt_create": "<ISO 8601 timestamp>",
-      "gmt_modified": "<ISO 8601 timestamp>"
-    }
-  ]
+// indent prefixes every line of s with n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
 }
 
-Analyze ALL source files. Be thorough. Include actual code references.
-Do NOT modify any source code. Only create/modify files within %s/.`, cfg.WikiPath, cfg.Language, cfg.WikiPath, cfg.Language, cfg.WikiPath)
+func newPromptData(cfg *config.Config, mode string) PromptData {
+	return PromptData{
+		Mode:         mode,
+		WikiPath:     cfg.WikiPath,
+		Language:     cfg.Language,
+		ContentDir:   cfg.WikiPath + "/" + cfg.Language + "/content",
+		MetadataFile: cfg.WikiPath + "/" + cfg.Language + "/meta/repowiki-metadata.json",
+		Sections:     cfg.WikiSections(),
+	}
 }
 
-func BuildIncrementalPrompt(cfg *config.Config, changedFiles []string, affectedSections []string) string {
-	fileList := "  - " + strings.Join(changedFiles, "\n  - ")
+func BuildFullGeneratePrompt(gitRoot string, cfg *config.Config) (string, error) {
+	return RenderPrompt(gitRoot, newPromptData(cfg, ModeFull))
+}
 
-	sectionHint := ""
-	if len(affectedSections) > 0 {
-		sectionHint = fmt.Sprintf(`
-POTENTIALLY AFFECTED WIKI SECTIONS (check and update these first):
-  - %s
-`, strings.Join(affectedSections, "\n  - "))
+// BuildIncrementalPrompt renders the prompt for an update to commitHash,
+// including the diffs of changedFiles, the changed Go symbols and the commit
+// messages since the last processed commit.
+func BuildIncrementalPrompt(gitRoot string, cfg *config.Config, changes []git.FileChange, affectedSections []string, symbols []SymbolChange, commitHash string) (string, error) {
+	data := newPromptData(cfg, ModeIncremental)
+	data.ChangedFiles = currentPaths(changes)
+	refs, err := BuildRefIndex(gitRoot, cfg)
+	if err != nil {
+		logf(gitRoot, "cannot index wiki citations: %v", err)
+	}
+	for _, c := range changes {
+		switch c.Status {
+		case git.StatusRenamed:
+			data.RenamedFiles = append(data.RenamedFiles, RenamedFile{From: c.OldPath, To: c.Path})
+		case git.StatusDeleted:
+			data.DeletedFiles = append(data.DeletedFiles, DeletedFile{Path: c.Path, Pages: refs.PagesCiting(c.Path)})
+		}
+	}
+	data.AffectedSections = affectedSections
+	data.Symbols = symbols
+	if len(symbols) > maxPromptSymbols {
+		logf(gitRoot, "%d changed Go symbols, leaving %d out of the prompt", len(symbols), len(symbols)-maxPromptSymbols)
+		data.Symbols = symbols[:maxPromptSymbols]
 	}
+	from, to := commitRange(cfg, commitHash)
+	data.Diffs = collectDiffs(gitRoot, cfg, from, to, changes)
+	data.Commits = collectCommits(gitRoot, cfg, from, to)
+	return RenderPrompt(gitRoot, data)
+}
 
-	return fmt.Sprintf(`You are a technical documentation specialist. Update the repository wiki to reflect recent code changes.
+// PromptModes lists the modes that have a built-in prompt template.
+func PromptModes() []string {
+	entries, _ := builtinPrompts.ReadDir("prompts")
+	modes := make([]string, 0, len(entries))
+	for _, e := range entries {
+		modes = append(modes, strings.TrimSuffix(e.Name(), ".tmpl"))
+	}
+	sort.Strings(modes)
+	return modes
+}
 
-CHANGED SOURCE FILES:
-%s
-%s
-INSTRUCTIONS:
-1. Read each changed source file to understand what was modified
-2. Read the existing wiki pages in %s/%s/content/
-3. Update ONLY the wiki sections affected by the code changes
-4. If a changed file introduces new functionality not covered by existing pages, create a new page
-5. Update %s/%s/meta/repowiki-metadata.json with any new or modified code snippet references
-6. Preserve existing formatting: <cite> blocks, Table of Contents, mermaid diagrams
-7. Do NOT modify any source code. Only modify files within %s/
+// DefaultPromptTemplate returns the built-in template of a mode.
+func DefaultPromptTemplate(mode string) (string, error) {
+	data, err := builtinPrompts.ReadFile("prompts/" + mode + ".tmpl")
+	if err != nil {
+		return "", fmt.Errorf("no built-in prompt template for mode %q", mode)
+	}
+	return string(data), nil
+}
 
-Keep documentation accurate and synchronized with the current codebase.`, fileList, sectionHint, cfg.WikiPath, cfg.Language, cfg.WikiPath, cfg.Language, cfg.WikiPath)
+// PromptTemplatePath is where a user override of a mode's template lives.
+func PromptTemplatePath(gitRoot string, mode string) string {
+	return filepath.Join(config.PromptsPath(gitRoot), mode+".tmpl")
+}
+
+// RenderPrompt executes the template of data.Mode, preferring the user's
+// override over the built-in template.
+func RenderPrompt(gitRoot string, data PromptData) (string, error) {
+	tmpl, err := loadPromptTemplate(gitRoot, data.Mode)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		// Template errors start with "template: <name>:<line>:".
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func loadPromptTemplate(gitRoot string, mode string) (*template.Template, error) {
+	text, err := os.ReadFile(PromptTemplatePath(gitRoot, mode))
+	name := filepath.Join(config.ConfigDir, config.PromptsDir, mode+".tmpl")
+	if errors.Is(err, fs.ErrNotExist) {
+		var def string
+		if def, err = DefaultPromptTemplate(mode); err != nil {
+			return nil, err
+		}
+		text, name = []byte(def), mode+" (built-in)"
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return template.New(name).Funcs(promptFuncs).Parse(string(text))
+}
+
+// CheckPromptTemplates parses every template override in .repowiki/prompts/
+// and renders it with sample data, so typos in variable names surface before
+// a hook run fails on them. It returns the overridden modes.
+func CheckPromptTemplates(gitRoot string, cfg *config.Config) ([]string, error) {
+	entries, err := os.ReadDir(config.PromptsPath(gitRoot))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var modes []string
+	for _, e := range entries {
+		mode, ok := strings.CutSuffix(e.Name(), ".tmpl")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if !containsString(PromptModes(), mode) {
+			return modes, fmt.Errorf("%s: unknown prompt mode %q (valid: %s)", filepath.Join(config.ConfigDir, config.PromptsDir, e.Name()), mode, strings.Join(PromptModes(), ", "))
+		}
+		data := newPromptData(cfg, mode)
+		data.ChangedFiles = []string{"main.go"}
+		data.RenamedFiles = []RenamedFile{{From: "util.go", To: "internal/util/util.go"}}
+		data.DeletedFiles = []DeletedFile{{Path: "legacy.go", Pages: []string{"Core Features/Legacy.md"}}}
+		data.AffectedSections = []string{"System Overview"}
+		data.Diffs = []FileDiff{{Path: "main.go", Diff: "@@ -1 +1 @@\n-old\n+new", Truncated: true}}
+		data.Commits = []CommitInfo{{Hash: "0123456789ab", Subject: "Change main", Body: "Details."}}
+		data.Symbols = []SymbolChange{{File: "main.go", Name: "main.Run", Kind: SymbolFunc, Change: SymbolChanged, Old: "func Run()", New: "func Run(args []string)", Pages: []string{"System Overview.md"}}}
+		data.Outline = &Outline{Pages: []OutlinePage{
+			{Path: "Overview.md", Title: "Overview", Description: "What it does.", Sources: []string{"README.md"}},
+			{Path: "Core Features/Main.md", Title: "Main", Sources: []string{"main.go"}},
+		}}
+		data.Page = &data.Outline.Pages[1]
+		data.Section = &data.Sections[0]
+		if _, err := RenderPrompt(gitRoot, data); err != nil {
+			return modes, err
+		}
+		modes = append(modes, mode)
+	}
+	return modes, nil
 }
@@ -0,0 +1,100 @@
+package wiki
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+func TestRenderPromptOverrides(t *testing.T) {
+	cfg := config.Default()
+	tests := []struct {
+		name     string
+		override string // "" renders the built-in template
+		want     string // substring of the prompt, or of the error
+		ok       bool
+	}{
+		{name: "built-in", want: cfg.WikiPath + "/" + cfg.Language + "/content", ok: true},
+		{name: "override", override: "Custom prompt for {{.ContentDir}} in {{.Language}}.", want: "Custom prompt for .qoder/repowiki/en/content in en.", ok: true},
+		{name: "override with funcs", override: `{{indent 2 .Mode}}`, want: "full", ok: true},
+		{name: "parse error", override: "{{.WikiPath", want: filepath.Join(config.ConfigDir, config.PromptsDir, "full.tmpl")},
+		{name: "missing field", override: "{{.Diff}}", want: "can't evaluate field Diff"},
+		{name: "unknown function", override: "{{upper .Mode}}", want: `function "upper" not defined`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if tt.override != "" {
+				writeTestFile(t, root, filepath.Join(config.ConfigDir, config.PromptsDir, "full.tmpl"), tt.override)
+			}
+			got, err := RenderPrompt(root, newPromptData(cfg, ModeFull))
+			if tt.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.override != "" && got != tt.want || !strings.Contains(got, tt.want) {
+					t.Errorf("RenderPrompt() = %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("RenderPrompt() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPromptOverrideIsPerMode(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.Default()
+	writeTestFile(t, root, filepath.Join(config.ConfigDir, config.PromptsDir, ModeFull+".tmpl"), "custom full prompt")
+
+	got, err := RenderPrompt(root, newPromptData(cfg, ModeIncremental))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := RenderPrompt(t.TempDir(), newPromptData(cfg, ModeIncremental))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != builtin {
+		t.Errorf("incremental prompt = %q, want the built-in one", got)
+	}
+	if _, err := RenderPrompt(root, newPromptData(cfg, "nonsense")); err == nil {
+		t.Error("RenderPrompt() of an unknown mode: no error")
+	}
+}
+
+func TestCheckPromptTemplates(t *testing.T) {
+	cfg := config.Default()
+	dir := filepath.Join(config.ConfigDir, config.PromptsDir)
+	tests := []struct {
+		name  string
+		files map[string]string
+		modes []string
+		err   string
+	}{
+		{name: "no overrides"},
+		{name: "valid", files: map[string]string{"full.tmpl": "{{.ContentDir}}", "notes.txt": "ignored"}, modes: []string{"full"}},
+		{name: "incremental fields", files: map[string]string{"incremental.tmpl": "{{range .Diffs}}{{.Path}}{{end}} {{(index .Symbols 0).Name}}"}, modes: []string{"incremental"}},
+		{name: "missing field", files: map[string]string{"page.tmpl": "{{.Page.Name}}"}, err: "can't evaluate field Name"},
+		{name: "unknown mode", files: map[string]string{"summary.tmpl": "hi"}, err: `unknown prompt mode "summary"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				writeTestFile(t, root, filepath.Join(dir, name), content)
+			}
+			modes, err := CheckPromptTemplates(root, cfg)
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("CheckPromptTemplates() error = %v, want %q", err, tt.err)
+			}
+			if tt.err == "" && strings.Join(modes, ",") != strings.Join(tt.modes, ",") {
+				t.Errorf("CheckPromptTemplates() = %v, want %v", modes, tt.modes)
+			}
+		})
+	}
+}
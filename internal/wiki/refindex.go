@@ -0,0 +1,212 @@
+package wiki
+
+import (
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// RefIndex records which source files each wiki page cites, and which
+// pages cite each source file. A page cites the link targets of its <cite>
+// blocks and every file:// link elsewhere in it; a link anchor such as
+// #L10-L20 limits the citation to those lines.
+type RefIndex struct {
+	files map[string][]string // page -> cited source files
+	pages map[string][]string // source file -> citing pages
+	lines map[string]map[string][]LineRange
+}
+
+var (
+	citeBlockRe = regexp.MustCompile(`(?is)<cite>(.*?)</cite>`)
+	// linkRe captures the target of a markdown link, with or without angle
+	// brackets, ignoring an optional title.
+	linkRe = regexp.MustCompile(`\]\(\s*(?:<([^>]*)>|([^)\s]+))[^)]*\)`)
+	// fileLinkRe finds bare file:// URLs in prose, where they may be quoted
+	// in backticks or followed by punctuation; see trimURLEnd.
+	fileLinkRe = regexp.MustCompile("file://[^)\\s>\"'\\]`]+")
+)
+
+// BuildRefIndex reads every page of the wiki content directory. Page paths
+// are relative to the content directory, source paths to the repository
+// root; both use forward slashes.
+func BuildRefIndex(gitRoot string, cfg *config.Config) (*RefIndex, error) {
+	x := &RefIndex{files: map[string][]string{}, pages: map[string][]string{}, lines: map[string]map[string][]LineRange{}}
+	var tracked map[string]bool
+	if files, err := git.ListFiles(gitRoot); err == nil {
+		tracked = make(map[string]bool, len(files))
+		for _, f := range files {
+			tracked[f] = true
+		}
+	}
+	err := walkPages(gitRoot, cfg, func(page string, content string) {
+		x.add(page, parseCitations(gitRoot, tracked, content))
+	})
+	for _, pages := range x.pages {
+		sort.Strings(pages)
+	}
+	return x, err
+}
+
+// walkPages calls fn with the path, relative to the content directory, and
+// the text of every wiki page. A missing content directory has no pages.
+func walkPages(gitRoot string, cfg *config.Config, fn func(page string, content string)) error {
+	contentDir := filepath.Join(gitRoot, cfg.WikiPath, cfg.Language, "content")
+	err := filepath.WalkDir(contentDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(contentDir, p)
+		fn(filepath.ToSlash(rel), string(data))
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (x *RefIndex) add(page string, cites map[string][]LineRange) {
+	if len(cites) == 0 {
+		return
+	}
+	x.files[page] = sortedKeys(cites)
+	x.lines[page] = cites
+	for f := range cites {
+		x.pages[f] = append(x.pages[f], page)
+	}
+}
+
+// PagesCiting returns the pages that cite file, sorted.
+func (x *RefIndex) PagesCiting(file string) []string {
+	return x.pages[file]
+}
+
+// FilesCitedBy returns the source files page cites, sorted.
+func (x *RefIndex) FilesCitedBy(page string) []string {
+	return x.files[page]
+}
+
+// CitedLines returns the line ranges of file that page cites; nil when it
+// cites the whole file.
+func (x *RefIndex) CitedLines(page string, file string) []LineRange {
+	return x.lines[page][file]
+}
+
+// Pages returns the pages that cite at least one file, sorted.
+func (x *RefIndex) Pages() []string {
+	return sortedKeys(x.files)
+}
+
+// Files returns every cited source file, sorted.
+func (x *RefIndex) Files() []string {
+	return sortedKeys(x.pages)
+}
+
+// parseCitations returns the normalized source paths a page cites, each
+// with the cited line ranges, or nil if it is cited as a whole anywhere on
+// the page. tracked lists the repository's files; see normalizeCitation.
+func parseCitations(gitRoot string, tracked map[string]bool, content string) map[string][]LineRange {
+	var targets []string
+	for _, block := range citeBlockRe.FindAllStringSubmatch(content, -1) {
+		for _, m := range linkRe.FindAllStringSubmatch(block[1], -1) {
+			targets = append(targets, m[1]+m[2])
+		}
+	}
+	for _, t := range fileLinkRe.FindAllString(content, -1) {
+		targets = append(targets, trimURLEnd(t))
+	}
+
+	cites := map[string][]LineRange{}
+	whole := map[string]bool{}
+	for _, t := range targets {
+		p, anchor, ok := normalizeCitation(gitRoot, tracked, t)
+		if !ok {
+			continue
+		}
+		if r, ok := ParseLineRange(anchor); ok {
+			// Links in <cite> blocks are also found as bare file:// URLs.
+			if !slices.Contains(cites[p], r) {
+				cites[p] = append(cites[p], r)
+			}
+		} else {
+			cites[p], whole[p] = nil, true
+		}
+	}
+	for p := range whole {
+		cites[p] = nil
+	}
+	return cites
+}
+
+// trimURLEnd drops sentence punctuation that follows a URL in prose.
+func trimURLEnd(u string) string {
+	return strings.TrimRight(u, ".,;:!?")
+}
+
+// normalizeCitation turns a link target into a repository-relative path and
+// its anchor, if any. It rejects web links and in-page anchors. Absolute
+// paths under gitRoot are made relative to it. Other absolute paths, and
+// relative ones leaving the repository, usually come from a wiki generated
+// in another checkout; they resolve to the longest of their trailing parts
+// that is a tracked file, and are rejected if none is or tracked is nil.
+func normalizeCitation(gitRoot string, tracked map[string]bool, target string) (string, string, bool) {
+	target = strings.TrimSpace(target)
+	target, anchor, _ := strings.Cut(target, "#")
+	if i := strings.IndexByte(target, '?'); i >= 0 {
+		target = target[:i]
+	}
+	if rest, ok := strings.CutPrefix(target, "file://"); ok {
+		// file:///abs/path has an empty host and file://localhost/abs/path
+		// names the local one; file://rel/path is a sloppy relative path.
+		target = rest
+		if local, ok := strings.CutPrefix(rest, "localhost/"); ok {
+			target = "/" + local
+		}
+	} else if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+		return "", "", false
+	}
+	if dec, err := url.PathUnescape(target); err == nil {
+		target = dec
+	}
+	target = filepath.ToSlash(target)
+	if target == "" {
+		return "", "", false
+	}
+
+	target = path.Clean(target)
+	if rel, ok := strings.CutPrefix(target, path.Clean(filepath.ToSlash(gitRoot))+"/"); ok {
+		return rel, anchor, true
+	}
+	if target == "." {
+		return "", "", false
+	}
+	if !path.IsAbs(target) && !isWindowsAbs(target) && target != ".." && !strings.HasPrefix(target, "../") {
+		return target, anchor, true
+	}
+	parts := strings.Split(strings.TrimLeft(target, "/"), "/")
+	for i := range parts {
+		if p := strings.Join(parts[i:], "/"); tracked[p] {
+			return p, anchor, true
+		}
+	}
+	return "", "", false
+}
+
+// isWindowsAbs reports whether p starts with a drive letter, as in C:/src.
+func isWindowsAbs(p string) bool {
+	return len(p) >= 3 && p[1] == ':' && p[2] == '/' &&
+		('a' <= p[0] && p[0] <= 'z' || 'A' <= p[0] && p[0] <= 'Z')
+}
@@ -0,0 +1,64 @@
+package wiki
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testTracked = map[string]bool{
+	"internal/wiki/detect.go": true,
+	"internal/git/git.go":     true,
+	"README.md":               true,
+	"my file.go":              true,
+}
+
+func TestNormalizeCitation(t *testing.T) {
+	const root = "/home/dev/repo"
+	tests := []struct {
+		target string
+		path   string
+		anchor string
+		ok     bool
+	}{
+		{"internal/wiki/detect.go", "internal/wiki/detect.go", "", true},
+		{"./internal/wiki/detect.go#L10-L20", "internal/wiki/detect.go", "L10-L20", true},
+		{"file:///home/dev/repo/internal/git/git.go#L5", "internal/git/git.go", "L5", true},
+		{"file://localhost/home/dev/repo/README.md", "README.md", "", true},
+		{"/home/dev/repo/internal/git/git.go", "internal/git/git.go", "", true},
+		// Generated in another checkout: the longest tracked suffix wins.
+		{"file:///to/internal/wiki/detect.go", "internal/wiki/detect.go", "", true},
+		{"file:///Users/someone/src/repo/internal/git/git.go#L1-L3", "internal/git/git.go", "L1-L3", true},
+		{"file:///C:/work/repo/README.md", "README.md", "", true},
+		{"../../../internal/wiki/detect.go", "internal/wiki/detect.go", "", true},
+		{"file://internal/wiki/detect.go", "internal/wiki/detect.go", "", true},
+		{"my%20file.go?plain=1", "my file.go", "", true},
+		{"file:///elsewhere/unknown.go", "", "", false},
+		{"../outside.go", "", "", false},
+		{"https://example.com/internal/wiki/detect.go", "", "", false},
+		{"mailto:dev@example.com", "", "", false},
+		{"#overview", "", "", false},
+		{".", "", "", false},
+	}
+	for _, tt := range tests {
+		p, anchor, ok := normalizeCitation(root, testTracked, tt.target)
+		if p != tt.path || anchor != tt.anchor || ok != tt.ok {
+			t.Errorf("normalizeCitation(%q) = %q, %q, %v, want %q, %q, %v", tt.target, p, anchor, ok, tt.path, tt.anchor, tt.ok)
+		}
+	}
+}
+
+func TestParseCitations(t *testing.T) {
+	content := "# Detection\n\n" +
+		"<cite>\n**Referenced Files**\n- [detect.go](file:///to/internal/wiki/detect.go#L10-L20)\n- [git.go](<internal/git/git.go> \"title\")\n</cite>\n\n" +
+		"See `file:///home/dev/repo/internal/wiki/detect.go#L30-L40`.\n" +
+		"The README (file:///home/dev/repo/README.md), and [docs](https://example.com).\n"
+	got := parseCitations("/home/dev/repo", testTracked, content)
+	want := map[string][]LineRange{
+		"internal/wiki/detect.go": {{10, 20}, {30, 40}},
+		"internal/git/git.go":     nil,
+		"README.md":               nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCitations = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package wiki
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// RenamedFile is a source file moved between the two commits of an update.
+type RenamedFile struct {
+	From string
+	To   string
+}
+
+// DeletedFile is a source file deleted between the two commits of an
+// update, with the wiki pages that still cite it.
+type DeletedFile struct {
+	Path  string
+	Pages []string
+}
+
+// currentPaths returns the paths of the changes as of the newer commit,
+// leaving out deleted files.
+func currentPaths(changes []git.FileChange) []string {
+	var paths []string
+	for _, c := range changes {
+		if c.Status != git.StatusDeleted {
+			paths = append(paths, c.Path)
+		}
+	}
+	return paths
+}
+
+// changedPaths returns the paths of all changes, deleted files included.
+func changedPaths(changes []git.FileChange) []string {
+	paths := make([]string, len(changes))
+	for i, c := range changes {
+		paths[i] = c.Path
+	}
+	return paths
+}
+
+// diffPaths are the pathspecs that show a change's diff: both paths of a
+// rename, so its contents are diffed rather than shown as all new.
+func diffPaths(c git.FileChange) []string {
+	if c.Status == git.StatusRenamed {
+		return []string{c.OldPath, c.Path}
+	}
+	return []string{c.Path}
+}
+
+// applyRenames points the metadata snippets and page citations of renamed
+// files at their new paths, before the engine sees the wiki. It returns a
+// function that undoes the edits, for when the update fails before they are
+// committed.
+func applyRenames(gitRoot string, cfg *config.Config, changes []git.FileChange) (undo func()) {
+	var renames []RenamedFile
+	for _, c := range changes {
+		if c.Status == git.StatusRenamed {
+			renames = append(renames, RenamedFile{From: c.OldPath, To: c.Path})
+		}
+	}
+	if len(renames) == 0 {
+		return func() {}
+	}
+
+	var edits []fileEdit
+	if meta, err := loadMetadata(gitRoot, cfg); err == nil {
+		metaFile := metadataPath(gitRoot, cfg)
+		before, _ := os.ReadFile(metaFile)
+		moved := false
+		for _, r := range renames {
+			moved = meta.renamePath(r.From, r.To) || moved
+		}
+		if moved {
+			if err := saveMetadata(gitRoot, cfg, meta); err != nil {
+				logf(gitRoot, "failed to update metadata for renamed files: %v", err)
+			} else if after, err := os.ReadFile(metaFile); err == nil {
+				edits = append(edits, fileEdit{path: metaFile, before: before, after: after})
+			}
+		}
+	}
+
+	contentDir := filepath.Join(gitRoot, cfg.WikiPath, cfg.Language, "content")
+	pages := 0
+	walkPages(gitRoot, cfg, func(page string, content string) {
+		updated := content
+		for _, r := range renames {
+			updated = rewriteLinks(updated, r.From, r.To)
+		}
+		if updated == content {
+			return
+		}
+		full := filepath.Join(contentDir, filepath.FromSlash(page))
+		if err := os.WriteFile(full, []byte(updated), 0644); err != nil {
+			logf(gitRoot, "failed to update links in %s: %v", page, err)
+			return
+		}
+		edits = append(edits, fileEdit{path: full, before: []byte(content), after: []byte(updated)})
+		pages++
+	})
+	logf(gitRoot, "%d file(s) renamed, links updated in %d page(s)", len(renames), pages)
+
+	return func() {
+		restored := 0
+		for _, e := range edits {
+			// Leave files the engine went on to edit.
+			if now, err := os.ReadFile(e.path); err != nil || !bytes.Equal(now, e.after) {
+				continue
+			}
+			if err := os.WriteFile(e.path, e.before, 0644); err != nil {
+				logf(gitRoot, "failed to restore %s: %v", e.path, err)
+				continue
+			}
+			restored++
+		}
+		logf(gitRoot, "update failed, undid rename edits in %d of %d file(s)", restored, len(edits))
+	}
+}
+
+// fileEdit is a write made by repowiki itself, kept so it can be undone.
+type fileEdit struct {
+	path   string
+	before []byte
+	after  []byte
+}
+
+// rewriteLinks points markdown links and file:// references to oldPath at
+// newPath. A link text naming the old file is renamed with it.
+func rewriteLinks(content string, oldPath string, newPath string) string {
+	q := regexp.QuoteMeta(oldPath)
+	link := regexp.MustCompile(`\[([^\]\n]*)\]\((\s*<?)(file://)?(\./)?` + q + `([)#?>\s"'])`)
+	content = link.ReplaceAllStringFunc(content, func(m string) string {
+		sm := link.FindStringSubmatch(m)
+		text := sm[1]
+		switch text {
+		case path.Base(oldPath):
+			text = path.Base(newPath)
+		case oldPath:
+			text = newPath
+		}
+		return "[" + text + "](" + sm[2] + sm[3] + sm[4] + newPath + sm[5]
+	})
+	bare := regexp.MustCompile(`file://` + q + `([)#?>\s"'\]]|$)`)
+	return bare.ReplaceAllStringFunc(content, func(m string) string {
+		return "file://" + newPath + m[len("file://")+len(oldPath):]
+	})
+}
@@ -0,0 +1,65 @@
+package wiki
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"[old.go](internal/old.go#L1-L5)", "[new.go](internal/pkg/new.go#L1-L5)"},
+		{"[internal/old.go](<./internal/old.go> \"src\")", "[internal/pkg/new.go](<./internal/pkg/new.go> \"src\")"},
+		{"[the loader](file://internal/old.go)", "[the loader](file://internal/pkg/new.go)"},
+		{"see file://internal/old.go#L3 and file://internal/old.go", "see file://internal/pkg/new.go#L3 and file://internal/pkg/new.go"},
+		{"[x](internal/old.go.bak)", "[x](internal/old.go.bak)"},
+		{"[x](internal/old.gone)", "[x](internal/old.gone)"},
+	}
+	for _, tt := range tests {
+		if got := rewriteLinks(tt.in, "internal/old.go", "internal/pkg/new.go"); got != tt.want {
+			t.Errorf("rewriteLinks(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRenamesUndo(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.Default()
+	content := ".qoder/repowiki/en/content/"
+	writeTestFile(t, root, content+"A.md", "<cite>[old.go](internal/old.go)</cite>\n")
+	writeTestFile(t, root, content+"B.md", "uses file://internal/old.go#L1-L9\n")
+	writeTestFile(t, root, content+"C.md", "unrelated\n")
+	if err := saveMetadata(root, cfg, &metadata{CodeSnippets: []codeSnippet{{Path: "internal/old.go", LineRange: "1-9"}}}); err != nil {
+		t.Fatal(err)
+	}
+	metaBefore := readTestFile(t, root, ".qoder/repowiki/en/meta/repowiki-metadata.json")
+
+	changes := []git.FileChange{{Status: git.StatusRenamed, OldPath: "internal/old.go", Path: "internal/new.go"}}
+	undo := applyRenames(root, cfg, changes)
+	if got := readTestFile(t, root, content+"A.md"); !strings.Contains(got, "internal/new.go") {
+		t.Fatalf("A.md not rewritten: %q", got)
+	}
+	if meta, _ := loadMetadata(root, cfg); meta.CodeSnippets[0].Path != "internal/new.go" {
+		t.Fatalf("metadata not rewritten: %+v", meta.CodeSnippets)
+	}
+
+	// The engine edited B.md before failing; that page is left alone.
+	writeTestFile(t, root, content+"B.md", "engine edit\n")
+	undo()
+	want := map[string]string{
+		content + "A.md": "<cite>[old.go](internal/old.go)</cite>\n",
+		content + "B.md": "engine edit\n",
+		content + "C.md": "unrelated\n",
+		".qoder/repowiki/en/meta/repowiki-metadata.json": metaBefore,
+	}
+	for p, w := range want {
+		if got := readTestFile(t, root, p); got != w {
+			t.Errorf("%s after undo = %q, want %q", p, got, w)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package wiki
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// maxRunLogs is how many per-run engine logs are kept in .repowiki/logs/runs/.
+const maxRunLogs = 50
+
+// runLog captures the output of a single engine run, line by line, as it is
+// produced. Lines are timestamped and tagged with their stream in the log
+// file and optionally mirrored verbatim to echo.
+type runLog struct {
+	mu      sync.Mutex
+	f       *os.File
+	echo    io.Writer
+	path    string
+	streams []*lineWriter
+}
+
+func openRunLog(gitRoot string, engine string, echo io.Writer) (*runLog, error) {
+	dir := config.RunLogPath(gitRoot)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run log dir: %w", err)
+	}
+	pruneRunLogs(dir, maxRunLogs-1)
+
+	// Runs started in the same second, as in parallel generation, get a
+	// numeric suffix.
+	base := fmt.Sprintf("%s-%s", time.Now().UTC().Format("2006-01-02T15-04-05"), engine)
+	var f *os.File
+	var path string
+	for i := 1; ; i++ {
+		name := base + ".log"
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d.log", base, i)
+		}
+		path = filepath.Join(dir, name)
+		var err error
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_APPEND|os.O_WRONLY, 0644)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to open run log: %w", err)
+		}
+	}
+	l := &runLog{f: f, echo: echo, path: path}
+	l.note("engine %s started", engine)
+	return l, nil
+}
+
+// stream returns a writer that feeds complete lines into the log under the
+// given stream name ("stdout" or "stderr").
+func (l *runLog) stream(name string) *lineWriter {
+	w := &lineWriter{log: l, name: name}
+	l.streams = append(l.streams, w)
+	return w
+}
+
+// note writes a repowiki-originated line into the run log.
+func (l *runLog) note(format string, args ...any) {
+	l.writeLine("repowiki", []byte(fmt.Sprintf(format, args...)), false)
+}
+
+// finish flushes any partial lines left in the streams and writes a closing
+// note. It must only be called once the engine process has exited.
+func (l *runLog) finish(format string, args ...any) {
+	for _, w := range l.streams {
+		w.Flush()
+	}
+	l.note(format, args...)
+}
+
+func (l *runLog) writeLine(stream string, line []byte, echo bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.f, "[%s] %s| %s\n", time.Now().UTC().Format("15:04:05"), stream, line)
+	if echo && l.echo != nil {
+		fmt.Fprintf(l.echo, "%s\n", line)
+	}
+}
+
+func (l *runLog) Close() error {
+	return l.f.Close()
+}
+
+// lineWriter splits written bytes into lines and forwards each complete line
+// to its run log. Call Flush once the writer is done to emit a trailing
+// partial line.
+type lineWriter struct {
+	log  *runLog
+	name string
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log.writeLine(w.name, bytes.TrimSuffix(w.buf[:i], []byte("\r")), true)
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.log.writeLine(w.name, w.buf, true)
+		w.buf = nil
+	}
+}
+
+// pruneRunLogs removes the oldest run logs so that at most keep remain.
+func pruneRunLogs(dir string, keep int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".log" {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-keep] {
+		os.Remove(filepath.Join(dir, name))
+	}
+}
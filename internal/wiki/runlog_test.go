@@ -0,0 +1,104 @@
+package wiki
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+func TestRunLogNaming(t *testing.T) {
+	root := t.TempDir()
+	nameRe := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}-claude-code(-\d+)?\.log$`)
+	seen := map[string]bool{}
+	// Runs in the same second get distinct files.
+	for i := 0; i < 3; i++ {
+		l, err := openRunLog(root, "claude-code", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		if filepath.Dir(l.path) != config.RunLogPath(root) {
+			t.Errorf("run log %s is outside %s", l.path, config.RunLogPath(root))
+		}
+		name := filepath.Base(l.path)
+		if !nameRe.MatchString(name) || seen[name] {
+			t.Errorf("run log name %q is malformed or reused", name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestRunLogPruning(t *testing.T) {
+	root := t.TempDir()
+	dir := config.RunLogPath(root)
+	rel, _ := filepath.Rel(root, dir)
+	for i := 0; i < maxRunLogs+5; i++ {
+		writeTestFile(t, root, filepath.Join(rel, fmt.Sprintf("2020-01-01T00-00-%02d-codex.log", i)), "old\n")
+	}
+	writeTestFile(t, root, filepath.Join(rel, "notes.txt"), "kept\n")
+
+	l, err := openRunLog(root, "codex", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	logs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(logs) != maxRunLogs {
+		t.Errorf("%d run logs kept, want %d", len(logs), maxRunLogs)
+	}
+	for _, gone := range []string{"2020-01-01T00-00-00-codex.log", "2020-01-01T00-00-05-codex.log"} {
+		if _, err := os.Stat(filepath.Join(dir, gone)); !os.IsNotExist(err) {
+			t.Errorf("oldest log %s not pruned", gone)
+		}
+	}
+	for _, kept := range []string{"2020-01-01T00-00-06-codex.log", "notes.txt", filepath.Base(l.path)} {
+		if _, err := os.Stat(filepath.Join(dir, kept)); err != nil {
+			t.Errorf("%s pruned: %v", kept, err)
+		}
+	}
+}
+
+func TestRunLogEcho(t *testing.T) {
+	root := t.TempDir()
+	var echo bytes.Buffer
+	l, err := openRunLog(root, "qoder", &echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := l.stream("stdout"), l.stream("stderr")
+	fmt.Fprint(stdout, "first line\r\nsecond ")
+	fmt.Fprint(stderr, "warning: slow\n")
+	fmt.Fprint(stdout, "half\nno newline")
+	l.finish("engine qoder finished")
+	l.Close()
+
+	if want := "first line\nwarning: slow\nsecond half\nno newline\n"; echo.String() != want {
+		t.Errorf("echo = %q, want %q", echo.String(), want)
+	}
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		// Drop the "[15:04:05] " timestamp.
+		got = append(got, line[strings.IndexByte(line, ']')+2:])
+	}
+	want := []string{
+		"repowiki| engine qoder started",
+		"stdout| first line",
+		"stderr| warning: slow",
+		"stdout| second half",
+		"stdout| no newline",
+		"repowiki| engine qoder finished",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("run log:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
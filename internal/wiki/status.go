@@ -0,0 +1,81 @@
+package wiki
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+const engineStatusFile = "engine-status.json"
+
+// EngineFailure is the last unresolved failure of an engine, kept so that
+// `repowiki status` can surface problems from background hook runs.
+type EngineFailure struct {
+	Kind    ErrorKind `json:"kind"`
+	Message string    `json:"message"`
+	Hint    string    `json:"hint,omitempty"`
+	Time    string    `json:"time"`
+}
+
+var engineStatusMu sync.Mutex
+
+func engineStatusPath(gitRoot string) string {
+	return filepath.Join(config.Dir(gitRoot), engineStatusFile)
+}
+
+// EngineFailures returns the last recorded failure per engine. Engines whose
+// most recent run succeeded are absent.
+func EngineFailures(gitRoot string) map[string]EngineFailure {
+	failures := map[string]EngineFailure{}
+	data, err := os.ReadFile(engineStatusPath(gitRoot))
+	if err != nil {
+		return failures
+	}
+	json.Unmarshal(data, &failures)
+	return failures
+}
+
+// recordEngineStatus stores err as the engine's last failure, or clears it
+// when err is nil. Interruptions by the user and budget stops are not
+// engine failures and are not recorded.
+func recordEngineStatus(gitRoot string, engine string, err error) {
+	var ee *EngineError
+	if err != nil && (!errors.As(err, &ee) || ee.Kind == ErrInterrupted || ee.Kind == ErrBudget) {
+		return
+	}
+
+	engineStatusMu.Lock()
+	defer engineStatusMu.Unlock()
+
+	failures := EngineFailures(gitRoot)
+	if err == nil {
+		if _, ok := failures[engine]; !ok {
+			return
+		}
+		delete(failures, engine)
+	} else {
+		msg, _, _ := strings.Cut(ee.Err.Error(), "\n")
+		failures[engine] = EngineFailure{
+			Kind:    ee.Kind,
+			Message: msg,
+			Hint:    ee.Hint,
+			Time:    time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+
+	if len(failures) == 0 {
+		os.Remove(engineStatusPath(gitRoot))
+		return
+	}
+	data, mErr := json.MarshalIndent(failures, "", "  ")
+	if mErr != nil {
+		return
+	}
+	os.WriteFile(engineStatusPath(gitRoot), append(data, '\n'), 0644)
+}
@@ -0,0 +1,274 @@
+package wiki
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
+)
+
+// Symbol-level impact analysis for Go sources: the exported declarations of
+// each changed .go file are compared between the two commits, so prompts
+// can say "signature of `wiki.RunEngine` changed" instead of naming a file.
+
+// maxPromptSymbols caps the symbol changes listed in one prompt; a new
+// package alone can add dozens.
+const maxPromptSymbols = 60
+
+// maxShownDecl is the longest declaration a prompt shows before and after a
+// change; a var holding a large literal is only named.
+const maxShownDecl = 240
+
+// Kinds of Go declarations a SymbolChange can be about.
+const (
+	SymbolFunc   = "func"
+	SymbolMethod = "method"
+	SymbolType   = "type"
+	SymbolConst  = "const"
+	SymbolVar    = "var"
+)
+
+// Changes a SymbolChange can report.
+const (
+	SymbolAdded   = "added"
+	SymbolRemoved = "removed"
+	SymbolChanged = "changed"
+)
+
+// SymbolChange is an exported Go identifier that was added, removed or
+// redeclared between two commits.
+type SymbolChange struct {
+	File string
+	// Name is qualified by package, and by receiver for methods, e.g.
+	// "wiki.RunEngine" or "config.Config.Validate".
+	Name   string
+	Kind   string
+	Change string
+	// Old and New are the declarations before and after a change of a
+	// func, method, const or var; empty for types and long declarations.
+	Old string
+	New string
+	// Pages are the wiki pages mentioning the identifier.
+	Pages []string
+}
+
+func (c SymbolChange) String() string {
+	switch {
+	case c.Change != SymbolChanged:
+		return fmt.Sprintf("%s `%s` %s", c.Kind, c.Name, c.Change)
+	case c.Kind == SymbolFunc || c.Kind == SymbolMethod:
+		return fmt.Sprintf("signature of `%s` changed", c.Name)
+	case c.Kind == SymbolType:
+		return fmt.Sprintf("definition of type `%s` changed", c.Name)
+	default:
+		return fmt.Sprintf("type or value of %s `%s` changed", c.Kind, c.Name)
+	}
+}
+
+// goSymbol is an exported declaration as found in one version of a file.
+type goSymbol struct {
+	kind string
+	decl string
+}
+
+// SymbolChanges compares the exported declarations of the changed Go files
+// between the last processed commit and commitHash, and finds the wiki
+// pages mentioning each changed identifier. A renamed file is compared with
+// its old path. Test files and excluded paths are skipped.
+func SymbolChanges(gitRoot string, cfg *config.Config, changes []git.FileChange, commitHash string) []SymbolChange {
+	from, to := commitRange(cfg, commitHash)
+	if from == "" {
+		from = to + "^"
+	}
+	var symbols []SymbolChange
+	for _, c := range changes {
+		f := c.Path
+		if !strings.HasSuffix(f, ".go") || strings.HasSuffix(f, "_test.go") || isExcluded(f, cfg.ExcludedPaths) {
+			continue
+		}
+		old := f
+		if c.Status == git.StatusRenamed {
+			old = c.OldPath
+		}
+		oldSyms, oldErr := fileSymbols(gitRoot, from, old)
+		newSyms, newErr := fileSymbols(gitRoot, to, f)
+		if oldErr != nil && newErr != nil {
+			continue
+		}
+		symbols = append(symbols, diffSymbols(f, oldSyms, newSyms)...)
+	}
+	if len(symbols) > 0 {
+		findMentions(gitRoot, cfg, symbols)
+	}
+	return symbols
+}
+
+// fileSymbols parses path at rev. A missing or unparsable file has no
+// symbols and an error.
+func fileSymbols(gitRoot string, rev string, path string) (map[string]goSymbol, error) {
+	src, err := git.FileAt(gitRoot, rev, path)
+	if err != nil {
+		return nil, err
+	}
+	return parseGoSymbols(src)
+}
+
+// parseGoSymbols returns the exported declarations of a Go source file,
+// keyed by their qualified name. Declarations are printed without comments
+// and in canonical layout, so that only changes to the code itself count.
+func parseGoSymbols(src []byte) (map[string]goSymbol, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	pkg := file.Name.Name
+	syms := map[string]goSymbol{}
+	add := func(name string, kind string, node any) {
+		// Positions from an empty file set carry no line breaks, so the
+		// printer lays the node out afresh and knows of no comments.
+		var b bytes.Buffer
+		printer.Fprint(&b, token.NewFileSet(), node)
+		syms[pkg+"."+name] = goSymbol{kind: kind, decl: b.String()}
+	}
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			sig := &ast.FuncDecl{Name: d.Name, Type: d.Type}
+			if d.Recv == nil {
+				add(d.Name.Name, SymbolFunc, sig)
+				continue
+			}
+			recv := receiverName(d.Recv)
+			if !ast.IsExported(recv) {
+				continue
+			}
+			// Receiver names aren't part of the signature.
+			sig.Recv = &ast.FieldList{List: []*ast.Field{{Type: d.Recv.List[0].Type}}}
+			add(recv+"."+d.Name.Name, SymbolMethod, sig)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						spec := *s
+						spec.Doc, spec.Comment = nil, nil
+						add(s.Name.Name, SymbolType, &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{&spec}})
+					}
+				case *ast.ValueSpec:
+					kind := SymbolVar
+					if d.Tok == token.CONST {
+						kind = SymbolConst
+					}
+					for i, n := range s.Names {
+						if !n.IsExported() {
+							continue
+						}
+						one := &ast.ValueSpec{Names: []*ast.Ident{n}, Type: s.Type}
+						if i < len(s.Values) {
+							one.Values = []ast.Expr{s.Values[i]}
+						}
+						add(n.Name, kind, &ast.GenDecl{Tok: d.Tok, Specs: []ast.Spec{one}})
+					}
+				}
+			}
+		}
+	}
+	return syms, nil
+}
+
+// receiverName returns the type name of a method receiver, without pointer
+// or type parameters.
+func receiverName(recv *ast.FieldList) string {
+	if recv == nil || len(recv.List) == 0 {
+		return ""
+	}
+	t := recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	switch t := t.(type) {
+	case *ast.IndexExpr:
+		return exprName(t.X)
+	case *ast.IndexListExpr:
+		return exprName(t.X)
+	default:
+		return exprName(t)
+	}
+}
+
+func exprName(e ast.Expr) string {
+	if id, ok := e.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// diffSymbols lists the symbols added, removed or redeclared between two
+// versions of a file, sorted by name.
+func diffSymbols(file string, before map[string]goSymbol, after map[string]goSymbol) []SymbolChange {
+	var changes []SymbolChange
+	for name, o := range before {
+		n, ok := after[name]
+		switch {
+		case !ok:
+			changes = append(changes, SymbolChange{File: file, Name: name, Kind: o.kind, Change: SymbolRemoved})
+		case n.decl != o.decl:
+			c := SymbolChange{File: file, Name: name, Kind: n.kind, Change: SymbolChanged}
+			if n.kind != SymbolType && len(o.decl) <= maxShownDecl && len(n.decl) <= maxShownDecl {
+				c.Old, c.New = o.decl, n.decl
+			}
+			changes = append(changes, c)
+		}
+	}
+	for name, n := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, SymbolChange{File: file, Name: name, Kind: n.kind, Change: SymbolAdded})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// findMentions fills in the pages mentioning each change: by its name
+// qualified with package or receiver (`wiki.RunEngine`, `Config.Validate`)
+// anywhere, or by its bare name on pages citing its file.
+func findMentions(gitRoot string, cfg *config.Config, changes []SymbolChange) {
+	refs, err := BuildRefIndex(gitRoot, cfg)
+	if err != nil {
+		logf(gitRoot, "cannot index wiki citations: %v", err)
+	}
+	qualified := make([]*regexp.Regexp, len(changes))
+	bare := make([]*regexp.Regexp, len(changes))
+	for i, c := range changes {
+		parts := strings.Split(c.Name, ".")
+		qualified[i] = wordRe(strings.Join(parts[len(parts)-2:], "."))
+		bare[i] = wordRe(parts[len(parts)-1])
+	}
+	walkPages(gitRoot, cfg, func(page string, content string) {
+		cited := map[string]bool{}
+		for _, f := range refs.FilesCitedBy(page) {
+			cited[f] = true
+		}
+		for i := range changes {
+			if qualified[i].MatchString(content) || (cited[changes[i].File] && bare[i].MatchString(content)) {
+				changes[i].Pages = append(changes[i].Pages, page)
+			}
+		}
+	})
+}
+
+// wordRe matches s as a whole identifier or dotted name.
+func wordRe(s string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(s) + `($|[^\w])`)
+}
@@ -0,0 +1,146 @@
+package wiki
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGoSymbols(t *testing.T) {
+	src := `package config
+
+// Config is the configuration.
+type Config struct {
+	Engine string
+	hidden int
+}
+
+type internal struct{}
+
+func Load(root string) (*Config, error) { return nil, nil }
+
+func (c *Config) Validate() error { return nil }
+
+func (c *internal) Validate() error { return nil }
+
+func (c Config) helper() {}
+
+func helper() {}
+
+const (
+	DefaultTimeout = 20
+	defaultRetries = 2
+)
+
+var Engines, engines = []string{"a"}, 1
+`
+	syms, err := parseGoSymbols([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]string{}
+	for name, s := range syms {
+		kinds[name] = s.kind
+	}
+	want := map[string]string{
+		"config.Config":          SymbolType,
+		"config.Load":            SymbolFunc,
+		"config.Config.Validate": SymbolMethod,
+		"config.DefaultTimeout":  SymbolConst,
+		"config.Engines":         SymbolVar,
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("symbols = %v, want %v", kinds, want)
+	}
+	if got := syms["config.Load"].decl; got != "func Load(root string) (*Config, error)" {
+		t.Errorf("Load decl = %q", got)
+	}
+	if got := syms["config.Config.Validate"].decl; got != "func (*Config) Validate() error" {
+		t.Errorf("Validate decl = %q", got)
+	}
+}
+
+func TestSymbolChangesIgnoreCommentsAndLayout(t *testing.T) {
+	before := `package p
+
+type T struct {
+	A int
+	B string
+}
+
+func F(a int, b string) (int, error) { return 0, nil }
+
+const C = 1
+`
+	tests := []struct {
+		name  string
+		after string
+		want  []string // "Name change"
+	}{
+		{"comments and blank lines", `package p
+
+// T is documented now.
+type T struct {
+	A int // the A
+
+	// B is documented too.
+	B string
+}
+
+// F does things.
+func F(a int,
+	b string) (int, error) {
+	return 1, nil // body changes don't count
+}
+
+const C = 1 // one
+`, nil},
+		{"field added", `package p
+
+type T struct {
+	A int
+	B string
+	C bool
+}
+
+func F(a int, b string) (int, error) { return 0, nil }
+
+const C = 1
+`, []string{"p.T changed"}},
+		{"signature and value changed, func added", `package p
+
+type T struct {
+	A int
+	B string
+}
+
+func F(a int, b string, c bool) (int, error) { return 0, nil }
+
+func G() {}
+
+const C = 2
+`, []string{"p.C changed", "p.F changed", "p.G added"}},
+		{"type removed", `package p
+
+func F(a int, b string) (int, error) { return 0, nil }
+
+const C = 1
+`, []string{"p.T removed"}},
+	}
+	old, err := parseGoSymbols([]byte(before))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		now, err := parseGoSymbols([]byte(tt.after))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, c := range diffSymbols("p.go", old, now) {
+			got = append(got, c.Name+" "+c.Change)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package wiki
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+// RunRecord is one line of .repowiki/runs.jsonl: what a single engine run
+// did and what it cost.
+type RunRecord struct {
+	Time            string  `json:"time"`
+	Engine          string  `json:"engine"`
+	Mode            string  `json:"mode"`
+	Status          string  `json:"status"` // "ok" or the ErrorKind of the failure
+	DurationSeconds float64 `json:"duration_seconds"`
+	Usage
+}
+
+// RunStatusOK is the Status of a successful run.
+const RunStatusOK = "ok"
+
+var runRecordMu sync.Mutex
+
+// LoadRunRecords returns all recorded runs, oldest first. Unreadable lines
+// are skipped.
+func LoadRunRecords(gitRoot string) ([]RunRecord, error) {
+	f, err := os.Open(config.RunsPath(gitRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []RunRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r RunRecord
+		if json.Unmarshal(sc.Bytes(), &r) == nil {
+			records = append(records, r)
+		}
+	}
+	return records, sc.Err()
+}
+
+// recordRun appends a record for one engine run. For failed runs, usage is
+// whatever the engine reported before it stopped.
+func recordRun(gitRoot string, cfg *config.Config, mode string, start time.Time, usage Usage, err error) {
+	r := RunRecord{
+		Time:            start.UTC().Format(time.RFC3339),
+		Engine:          cfg.Engine,
+		Mode:            mode,
+		Status:          RunStatusOK,
+		DurationSeconds: time.Since(start).Round(time.Second).Seconds(),
+		Usage:           usage,
+	}
+	if err != nil {
+		r.Status = string(ErrorKindOf(err))
+	}
+	if r.Model == "" {
+		r.Model = cfg.Model
+	}
+	data, mErr := json.Marshal(r)
+	if mErr != nil {
+		return
+	}
+
+	runRecordMu.Lock()
+	defer runRecordMu.Unlock()
+	f, oErr := os.OpenFile(config.RunsPath(gitRoot), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if oErr != nil {
+		logf(gitRoot, "failed to record run: %v", oErr)
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
@@ -1,81 +1,201 @@
 package wiki
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/ikrasnodymov/repowiki/internal/config"
+	"github.com/ikrasnodymov/repowiki/internal/git"
 	"github.com/ikrasnodymov/repowiki/internal/lockfile"
 )
 
-// FullGenerate performs a complete wiki generation from scratch.
-func FullGenerate(gitRoot string, cfg *config.Config, commitHash string) error {
+// Options tweaks how a generation or update run behaves.
+type Options struct {
+	// Echo, if set, receives engine output live as it is written to the run log.
+	Echo io.Writer
+	// IgnoreBudget skips the daily and monthly budget check.
+	IgnoreBudget bool
+}
+
+// FullGenerate performs a complete wiki generation from scratch, in one
+// engine run or, with generation "sections" or "outline", one run per
+// section or outline page. When only some of those runs fail, the pages
+// that were written are committed and the error lists the failed ones.
+func FullGenerate(ctx context.Context, gitRoot string, cfg *config.Config, commitHash string, opts Options) error {
+	if err := checkConfig(gitRoot, cfg); err != nil {
+		return err
+	}
 	if err := lockfile.Acquire(gitRoot); err != nil {
 		return fmt.Errorf("cannot acquire lock: %w", err)
 	}
 	defer lockfile.Release(gitRoot)
 
-	logf(gitRoot, "starting full wiki generation")
+	if err := checkBudget(gitRoot, cfg, opts); err != nil {
+		return err
+	}
 
-	prompt := BuildFullGeneratePrompt(cfg)
+	logf(gitRoot, "starting full wiki generation")
 
-	output, err := RunEngine(cfg, gitRoot, prompt)
+	snap, err := snapshotTree(gitRoot, cfg)
 	if err != nil {
+		return fmt.Errorf("wiki generation failed: %w", err)
+	}
+	var res *Result
+	switch cfg.Generation {
+	case config.GenerationSections:
+		res, err = generateSections(ctx, gitRoot, cfg, opts)
+	case config.GenerationOutline:
+		res, err = generateFromOutline(ctx, gitRoot, cfg, opts)
+	default:
+		res, err = generateSingle(ctx, gitRoot, cfg, opts)
+	}
+	if gErr := snap.enforce(); gErr != nil {
+		return fmt.Errorf("wiki generation aborted: %w", gErr)
+	}
+	var split *SplitError
+	if err != nil && !errors.As(err, &split) {
 		logf(gitRoot, "engine failed: %v", err)
 		return fmt.Errorf("wiki generation failed: %w", err)
 	}
 
-	logf(gitRoot, "engine completed, output length: %d", len(output))
+	logf(gitRoot, "engine %s completed, output length: %d%s", res.Engine, len(res.Output), res.Usage.summary())
+	if split != nil {
+		logf(gitRoot, "wiki generation incomplete: %v", split)
+	}
 
 	if cfg.AutoCommit {
-		config.UpdateLastRun(gitRoot, commitHash)
-		if err := CommitChanges(gitRoot, cfg, "full wiki generation"); err != nil {
+		desc := "full wiki generation"
+		if split == nil {
+			config.UpdateLastRun(gitRoot, commitHash)
+		} else {
+			desc = fmt.Sprintf("partial wiki generation (%d of %d %ss failed)", len(split.Failed), split.Total, split.Unit)
+		}
+		if err := CommitChanges(gitRoot, cfg, desc); err != nil {
 			logf(gitRoot, "auto-commit failed: %v", err)
 			return err
 		}
-		logf(gitRoot, "wiki changes committed")
+		logf(gitRoot, "wiki changes committed (engine: %s)", res.Engine)
 	}
 
+	if split != nil {
+		return fmt.Errorf("wiki generation incomplete: %w", split)
+	}
 	return nil
 }
 
-// IncrementalUpdate updates wiki for specific changed files.
-func IncrementalUpdate(gitRoot string, cfg *config.Config, changedFiles []string, commitHash string) error {
+// generateSingle writes the whole wiki in one engine run.
+func generateSingle(ctx context.Context, gitRoot string, cfg *config.Config, opts Options) (*Result, error) {
+	prompt, err := BuildFullGeneratePrompt(gitRoot, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return RunEngine(ctx, cfg, gitRoot, Request{Mode: ModeFull, Prompt: prompt}, opts)
+}
+
+// generateSections writes each section of the wiki outline in its own
+// engine run.
+func generateSections(ctx context.Context, gitRoot string, cfg *config.Config, opts Options) (*Result, error) {
+	units, err := sectionUnits(gitRoot, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return runUnits(ctx, gitRoot, cfg, units, opts)
+}
+
+// IncrementalUpdate updates wiki for specific changed files. Citations of
+// renamed files are pointed at their new paths first, and pointed back if
+// the update fails; deleted files are named to the engine as such.
+func IncrementalUpdate(ctx context.Context, gitRoot string, cfg *config.Config, changes []git.FileChange, commitHash string, opts Options) error {
+	if err := checkConfig(gitRoot, cfg); err != nil {
+		return err
+	}
 	if err := lockfile.Acquire(gitRoot); err != nil {
 		return fmt.Errorf("cannot acquire lock: %w", err)
 	}
 	defer lockfile.Release(gitRoot)
 
-	logf(gitRoot, "starting incremental update for %d files", len(changedFiles))
+	if err := checkBudget(gitRoot, cfg, opts); err != nil {
+		return err
+	}
+
+	logf(gitRoot, "starting incremental update for %d files", len(changes))
 
-	affectedSections := AffectedSections(gitRoot, cfg, changedFiles)
+	undoRenames := applyRenames(gitRoot, cfg, changes)
+	engineDone := false
+	defer func() {
+		if !engineDone {
+			undoRenames()
+		}
+	}()
+	symbols := SymbolChanges(gitRoot, cfg, changes, commitHash)
+	if len(symbols) > 0 {
+		logf(gitRoot, "%d changed Go symbols", len(symbols))
+	}
+	affectedSections := AffectedSections(gitRoot, cfg, changes, commitHash, symbols)
 	logf(gitRoot, "affected sections: %v", affectedSections)
 
-	prompt := BuildIncrementalPrompt(cfg, changedFiles, affectedSections)
+	prompt, err := BuildIncrementalPrompt(gitRoot, cfg, changes, affectedSections, symbols, commitHash)
+	if err != nil {
+		logf(gitRoot, "cannot build prompt: %v", err)
+		return fmt.Errorf("wiki update failed: %w", err)
+	}
 
-	output, err := RunEngine(cfg, gitRoot, prompt)
+	snap, err := snapshotTree(gitRoot, cfg)
+	if err != nil {
+		return fmt.Errorf("wiki update failed: %w", err)
+	}
+	res, err := RunEngine(ctx, cfg, gitRoot, Request{Mode: ModeIncremental, Prompt: prompt, Files: changedPaths(changes)}, opts)
+	if gErr := snap.enforce(); gErr != nil {
+		return fmt.Errorf("wiki update aborted: %w", gErr)
+	}
 	if err != nil {
 		logf(gitRoot, "engine failed: %v", err)
 		return fmt.Errorf("wiki update failed: %w", err)
 	}
 
-	logf(gitRoot, "engine completed, output length: %d", len(output))
+	engineDone = true
+	logf(gitRoot, "engine %s completed, output length: %d%s", res.Engine, len(res.Output), res.Usage.summary())
 
 	if cfg.AutoCommit {
 		config.UpdateLastRun(gitRoot, commitHash)
-		desc := fmt.Sprintf("update wiki for %d changed files", len(changedFiles))
+		desc := fmt.Sprintf("update wiki for %d changed files", len(changes))
 		if err := CommitChanges(gitRoot, cfg, desc); err != nil {
 			logf(gitRoot, "auto-commit failed: %v", err)
 			return err
 		}
-		logf(gitRoot, "wiki changes committed")
+		logf(gitRoot, "wiki changes committed (engine: %s)", res.Engine)
 	}
 
 	return nil
 }
 
+// checkConfig logs and returns a validation error of cfg, so that a typo
+// in config.json fails the run instead of being ignored.
+func checkConfig(gitRoot string, cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		logf(gitRoot, "invalid config: %v", err)
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// checkBudget logs and returns CheckBudget's error unless opts overrides it.
+func checkBudget(gitRoot string, cfg *config.Config, opts Options) error {
+	if opts.IgnoreBudget {
+		return nil
+	}
+	if err := CheckBudget(gitRoot, cfg); err != nil {
+		logf(gitRoot, "skipping run: %v", err)
+		return err
+	}
+	return nil
+}
+
 // Exists checks if the wiki directory has content.
 func Exists(gitRoot string, cfg *config.Config) bool {
 	contentPath := filepath.Join(gitRoot, cfg.WikiPath, cfg.Language, "content")
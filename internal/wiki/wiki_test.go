@@ -0,0 +1,31 @@
+package wiki
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikrasnodymov/repowiki/internal/config"
+)
+
+func TestRunsRejectInvalidConfig(t *testing.T) {
+	root := newTestRepo(t, map[string]string{"main.go": "package main\n"})
+	cfg := config.Default()
+	cfg.Engine = "fake"
+	cfg.WriteGuard = "sometimes"
+	ctx := context.Background()
+
+	if err := FullGenerate(ctx, root, cfg, "", Options{}); err == nil {
+		t.Error("FullGenerate: no error")
+	}
+	if err := IncrementalUpdate(ctx, root, cfg, nil, "", Options{}); err == nil {
+		t.Error("IncrementalUpdate: no error")
+	}
+	if _, err := GenerateOutline(ctx, root, cfg, Options{}); err == nil {
+		t.Error("GenerateOutline: no error")
+	}
+	if _, err := os.Stat(filepath.Join(root, cfg.WikiPath)); !os.IsNotExist(err) {
+		t.Errorf("wiki directory written for an invalid config: %v", err)
+	}
+}